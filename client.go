@@ -0,0 +1,291 @@
+// client.go — Client replaces ad-hoc calls to the package-level call() with
+// a context-aware JSON-RPC client for one endpoint: a shared http.Client,
+// retry with exponential backoff + jitter on 429/5xx, JSON-RPC 2.0 batch
+// requests (so probeOnce's balance/nonce/code calls cost one HTTP round
+// trip instead of several), and per-endpoint call/error/latency metrics
+// exported via MetricsHandler. Real-time subscriptions for ws(s):// RPC
+// URLs live in ws.go.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Client is a JSON-RPC client bound to one RPC endpoint.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	maxRetries int
+
+	metricsMu sync.Mutex
+	calls     int64
+	errors    int64
+	latencies []time.Duration // capped ring buffer for p50/p99
+
+	wsMu     sync.Mutex
+	ws       *wsConn
+	subs     map[string]chan json.RawMessage
+	pending  map[int]chan json.RawMessage
+	wsNextID int
+}
+
+const maxLatencySamples = 1000
+
+// NewClient builds a Client for url with the given per-request timeout.
+func NewClient(url string, timeout time.Duration) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+		maxRetries: 4,
+	}
+}
+
+// RPCCall is one method+params pair for Client.BatchCall.
+type RPCCall struct {
+	Method string
+	Params []interface{}
+}
+
+// BatchResult is one call's outcome within a batch, in request order.
+type BatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// Call issues a single JSON-RPC request, retrying on 429/5xx responses and
+// transport errors with exponential backoff and jitter.
+func (c *Client) Call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	results, err := c.doBatch(ctx, []RPCCall{{Method: method, Params: params}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0].Result, results[0].Err
+}
+
+// BatchCall issues every call as one JSON-RPC 2.0 batch request (one HTTP
+// round trip) and returns one BatchResult per call, in the same order.
+func (c *Client) BatchCall(ctx context.Context, calls ...RPCCall) ([]BatchResult, error) {
+	return c.doBatch(ctx, calls)
+}
+
+func (c *Client) doBatch(ctx context.Context, calls []RPCCall) ([]BatchResult, error) {
+	reqs := make([]rpcReq, len(calls))
+	for i, rc := range calls {
+		reqs[i] = rpcReq{Jsonrpc: "2.0", Method: rc.Method, Params: rc.Params, Id: i + 1}
+	}
+	var payload []byte
+	var err error
+	if len(reqs) == 1 {
+		payload, err = json.Marshal(reqs[0])
+	} else {
+		payload, err = json.Marshal(reqs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.doWithRetry(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reqs) == 1 {
+		var single rpcRes
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil, err
+		}
+		return []BatchResult{resultOf(single)}, nil
+	}
+
+	var batch []rpcRes
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	byID := make(map[int]rpcRes, len(batch))
+	for _, r := range batch {
+		byID[r.Id] = r
+	}
+	out := make([]BatchResult, len(reqs))
+	for i, req := range reqs {
+		r, ok := byID[req.Id]
+		if !ok {
+			out[i] = BatchResult{Err: fmt.Errorf("batch response missing id %d", req.Id)}
+			continue
+		}
+		out[i] = resultOf(r)
+	}
+	return out, nil
+}
+
+func resultOf(r rpcRes) BatchResult {
+	if r.Error != nil {
+		return BatchResult{Err: fmt.Errorf("rpc error %d: %s", r.Error.Code, r.Error.Message)}
+	}
+	return BatchResult{Result: r.Result}
+}
+
+// doWithRetry POSTs payload, retrying on 429/5xx and transport errors with
+// exponential backoff + jitter, up to c.maxRetries attempts.
+func (c *Client) doWithRetry(ctx context.Context, payload []byte) ([]byte, error) {
+	backoff := 200 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		status, body, err := c.doOnce(ctx, payload)
+		c.record(time.Since(start), err != nil || status >= 400)
+
+		if err == nil && status < 400 {
+			return body, nil
+		}
+		retriable := err != nil || status == 429 || status >= 500
+		if !retriable || attempt >= c.maxRetries {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("rpc http %d", status)
+		}
+		jitter := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-time.After(backoff + jitter):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) doOnce(ctx context.Context, payload []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+func (c *Client) record(d time.Duration, isError bool) {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+	c.calls++
+	if isError {
+		c.errors++
+	}
+	c.latencies = append(c.latencies, d)
+	if len(c.latencies) > maxLatencySamples {
+		c.latencies = c.latencies[len(c.latencies)-maxLatencySamples:]
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// writeMetricSamples writes this client's call/error/latency counters as
+// Prometheus sample lines only - no "# HELP"/"# TYPE" lines, since those are
+// per metric family, not per client. Callers serving more than one Client on
+// the same /metrics response must write the family headers once themselves
+// (see writeMetricsFamilies) and then call this per client; see
+// MetricsHandler for the single-client case.
+func (c *Client) writeMetricSamples(w io.Writer) {
+	c.metricsMu.Lock()
+	calls, errs := c.calls, c.errors
+	lat := append([]time.Duration(nil), c.latencies...)
+	c.metricsMu.Unlock()
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	p50, p99 := percentile(lat, 0.50), percentile(lat, 0.99)
+
+	fmt.Fprintf(w, "secure_probe_rpc_calls_total{endpoint=%q} %d\n", c.url, calls)
+	fmt.Fprintf(w, "secure_probe_rpc_errors_total{endpoint=%q} %d\n", c.url, errs)
+	fmt.Fprintf(w, "secure_probe_rpc_latency_seconds{endpoint=%q,quantile=\"0.5\"} %f\n", c.url, p50.Seconds())
+	fmt.Fprintf(w, "secure_probe_rpc_latency_seconds{endpoint=%q,quantile=\"0.99\"} %f\n", c.url, p99.Seconds())
+}
+
+// writeMetricsFamilies writes the "# HELP"/"# TYPE" lines shared by every
+// Client's metrics, once. Prometheus text exposition format requires each
+// metric family's HELP/TYPE to appear at most once in a scrape, so any
+// handler serving multiple clients (see writeMetrics) must call this exactly
+// once regardless of how many clients it then writes samples for.
+func writeMetricsFamilies(w io.Writer) {
+	fmt.Fprintf(w, "# HELP secure_probe_rpc_calls_total Total JSON-RPC calls issued\n")
+	fmt.Fprintf(w, "# TYPE secure_probe_rpc_calls_total counter\n")
+	fmt.Fprintf(w, "# HELP secure_probe_rpc_errors_total Total JSON-RPC call errors\n")
+	fmt.Fprintf(w, "# TYPE secure_probe_rpc_errors_total counter\n")
+	fmt.Fprintf(w, "# HELP secure_probe_rpc_latency_seconds RPC call latency quantiles\n")
+	fmt.Fprintf(w, "# TYPE secure_probe_rpc_latency_seconds gauge\n")
+}
+
+// MetricsHandler serves this client's call/error/latency counters in
+// Prometheus text exposition format. For a handler that serves several
+// clients on one /metrics response without repeating HELP/TYPE per client,
+// use writeMetrics instead.
+func (c *Client) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeMetricsFamilies(w)
+		c.writeMetricSamples(w)
+	}
+}
+
+// writeMetrics serves Prometheus metrics for every client in clients on a
+// single response: the "# HELP"/"# TYPE" family lines are written exactly
+// once, then each distinct *Client writes its own labeled sample lines.
+// Endpoints that share a *Client (same RPCURL, e.g. two chains proxied
+// through one gateway, see clientFor) are deduped so their metrics aren't
+// emitted twice.
+func writeMetrics(w io.Writer, clients []*Client) {
+	writeMetricsFamilies(w)
+	seen := make(map[*Client]bool, len(clients))
+	for _, c := range clients {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		c.writeMetricSamples(w)
+	}
+}
+
+// clientFor caches one Client per RPC URL so existing call(url, ...) call
+// sites share the same connection pool, retry policy, and metrics instead
+// of each building a one-off http.Client.
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*Client{}
+)
+
+func clientFor(url string) *Client {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	c, ok := clients[url]
+	if !ok {
+		c = NewClient(url, 10*time.Second)
+		clients[url] = c
+	}
+	return c
+}
+
+// call is a convenience wrapper around a cached Client for existing call
+// sites that don't need context cancellation or batching; see Client for
+// those.
+func call(url, method string, params ...interface{}) (json.RawMessage, error) {
+	return clientFor(url).Call(context.Background(), method, params...)
+}
@@ -0,0 +1,212 @@
+// codewindow.go — scans eth_getLogs over the trailing block window for
+// ERC-20 Approval/Transfer events touching addr, flagging unlimited
+// approvals, approvals to freshly-deployed spenders, and high-volume
+// outbound transfers (sweep behavior).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// keccak256("Approval(address,address,uint256)")
+	topicApproval = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	// keccak256("Transfer(address,address,uint256)")
+	topicTransfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	defaultCodeWindowBlocks = 50
+	defaultRPCBatchSize     = 25
+	defaultSweepTxThreshold = 10
+	maxUint256Hex           = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+)
+
+// codeWindowConfig parameterizes the log scan; the zero value is not usable,
+// use defaultCodeWindowConfig().
+type codeWindowConfig struct {
+	WindowBlocks  int64
+	RPCBatchSize  int64
+	SweepTxThresh int
+}
+
+func defaultCodeWindowConfig() codeWindowConfig {
+	return codeWindowConfig{
+		WindowBlocks:  defaultCodeWindowBlocks,
+		RPCBatchSize:  defaultRPCBatchSize,
+		SweepTxThresh: defaultSweepTxThreshold,
+	}
+}
+
+// CodeWindowConfig is the operator-facing, config-file shape of
+// codeWindowConfig: a zero field means "use the default". It lives on
+// ChainThresholds (multichain.go) so window size, RPC batch size, and the
+// sweep threshold can be tuned per chain like the rest of the thresholds.
+type CodeWindowConfig struct {
+	WindowBlocks     int64 `json:"windowBlocks" yaml:"windowBlocks"`
+	RPCBatchSize     int64 `json:"rpcBatchSize" yaml:"rpcBatchSize"`
+	SweepTxThreshold int   `json:"sweepTxThreshold" yaml:"sweepTxThreshold"`
+}
+
+// resolve fills in defaults for any unset field.
+func (c CodeWindowConfig) resolve() codeWindowConfig {
+	cfg := defaultCodeWindowConfig()
+	if c.WindowBlocks > 0 {
+		cfg.WindowBlocks = c.WindowBlocks
+	}
+	if c.RPCBatchSize > 0 {
+		cfg.RPCBatchSize = c.RPCBatchSize
+	}
+	if c.SweepTxThreshold > 0 {
+		cfg.SweepTxThresh = c.SweepTxThreshold
+	}
+	return cfg
+}
+
+type rpcLog struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber string   `json:"blockNumber"`
+}
+
+// addrTopic left-pads a 20-byte hex address to a 32-byte topic filter.
+func addrTopic(addr string) string {
+	a := strings.TrimPrefix(strings.ToLower(addr), "0x")
+	return "0x" + strings.Repeat("0", 64-len(a)) + a
+}
+
+// topicToAddr takes a 32-byte topic and returns the last 20 bytes as an
+// 0x-prefixed address.
+func topicToAddr(topic string) string {
+	t := strings.TrimPrefix(topic, "0x")
+	if len(t) < 40 {
+		return "0x" + t
+	}
+	return "0x" + t[len(t)-40:]
+}
+
+// scanCodeWindow runs the Approval/Transfer log scan described in the
+// package comment over [latest-window.WindowBlocks, latest], and appends
+// score/reasons to score/reasons accordingly. It chunks the range into
+// smaller sub-windows and retries when the RPC complains about too large a
+// result set, since many providers cap eth_getLogs result counts.
+func scanCodeWindow(rpc, addr string, latest *big.Int, cfg codeWindowConfig, score *int, reasons *[]string) {
+	start := new(big.Int).Sub(latest, big.NewInt(cfg.WindowBlocks))
+	if start.Sign() < 0 {
+		start = big.NewInt(0)
+	}
+
+	approvalLogs, err := getLogsChunked(rpc, start, latest, addr, topicApproval)
+	if err != nil {
+		*reasons = append(*reasons, fmt.Sprintf("code window approval scan failed: %v", err))
+	}
+	transferLogs, err := getLogsChunked(rpc, start, latest, addr, topicTransfer)
+	if err != nil {
+		*reasons = append(*reasons, fmt.Sprintf("code window transfer scan failed: %v", err))
+	}
+
+	validApprovals := approvalLogs[:0:0]
+	for _, l := range approvalLogs {
+		if len(l.Topics) >= 3 {
+			validApprovals = append(validApprovals, l)
+		}
+	}
+
+	startTag := fmt.Sprintf("0x%x", start)
+	client := clientFor(rpc)
+	batchSize := int(cfg.RPCBatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultRPCBatchSize
+	}
+	for i := 0; i < len(validApprovals); i += batchSize {
+		chunk := validApprovals[i:min(i+batchSize, len(validApprovals))]
+
+		// one eth_getCode(latest) + eth_getCode(windowStart) pair per log,
+		// issued as a single batched RPC call per cfg.RPCBatchSize logs
+		// instead of two sequential round trips per log.
+		calls := make([]RPCCall, 0, len(chunk)*2)
+		for _, l := range chunk {
+			spender := topicToAddr(l.Topics[2])
+			calls = append(calls,
+				RPCCall{Method: "eth_getCode", Params: []interface{}{spender, "latest"}},
+				RPCCall{Method: "eth_getCode", Params: []interface{}{spender, startTag}},
+			)
+		}
+		results, err := client.BatchCall(context.Background(), calls...)
+		if err != nil {
+			*reasons = append(*reasons, fmt.Sprintf("code window spender lookup failed: %v", err))
+			continue
+		}
+
+		for j, l := range chunk {
+			spender := topicToAddr(l.Topics[2])
+			amount := strings.TrimPrefix(strings.ToLower(l.Data), "0x")
+			unlimited := strings.TrimLeft(amount, "0") == strings.TrimLeft(maxUint256Hex, "0") && amount != ""
+
+			codeLatest, codeAtStart := results[2*j].Result, results[2*j+1].Result
+			freshContract := len(codeLatest) > 4 && len(codeAtStart) <= 4 // had no code at window start => deployed inside it
+
+			if unlimited {
+				*score += 20
+				*reasons = append(*reasons, fmt.Sprintf("unlimited ERC-20 approval to %s at block %s", spender, l.BlockNumber))
+			}
+			if freshContract {
+				*score += 20
+				*reasons = append(*reasons, fmt.Sprintf("approval spender %s deployed inside scan window (block %s)", spender, l.BlockNumber))
+			}
+		}
+	}
+
+	outboundTransfers := 0
+	for _, l := range transferLogs {
+		if len(l.Topics) < 2 {
+			continue
+		}
+		if strings.EqualFold(topicToAddr(l.Topics[1]), addr) {
+			outboundTransfers++
+		}
+	}
+	if outboundTransfers > cfg.SweepTxThresh {
+		*score += 15
+		*reasons = append(*reasons, fmt.Sprintf("%d outbound token transfers in %d-block window (sweep pattern)", outboundTransfers, cfg.WindowBlocks))
+	}
+}
+
+// getLogsChunked calls eth_getLogs for [start, end] filtered on topic0 and
+// the owner/from address in topics[1], splitting the range in half and
+// retrying whenever the provider reports too many results.
+func getLogsChunked(rpc string, start, end *big.Int, addr, topic0 string) ([]rpcLog, error) {
+	if start.Cmp(end) > 0 {
+		return nil, nil
+	}
+	filter := map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", start),
+		"toBlock":   fmt.Sprintf("0x%x", end),
+		"topics":    []interface{}{topic0, addrTopic(addr)},
+	}
+	raw, err := call(rpc, "eth_getLogs", filter)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "query returned more than") && start.Cmp(end) != 0 {
+			mid := new(big.Int).Add(start, end)
+			mid.Div(mid, big.NewInt(2))
+			left, lerr := getLogsChunked(rpc, start, mid, addr, topic0)
+			right, rerr := getLogsChunked(rpc, new(big.Int).Add(mid, big.NewInt(1)), end, addr, topic0)
+			if lerr != nil {
+				return nil, lerr
+			}
+			if rerr != nil {
+				return nil, rerr
+			}
+			return append(left, right...), nil
+		}
+		return nil, err
+	}
+	var logs []rpcLog
+	if err := json.Unmarshal(raw, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
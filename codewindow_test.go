@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddrTopicTopicToAddrRoundTrip(t *testing.T) {
+	addr := "0xabc0000000000000000000000000000000000000"
+	topic := addrTopic(addr)
+	if len(topic) != 66 { // "0x" + 64 hex chars
+		t.Fatalf("len(addrTopic(%s)) = %d, want 66", addr, len(topic))
+	}
+	if got := topicToAddr(topic); got != addr {
+		t.Fatalf("topicToAddr(addrTopic(%s)) = %s, want %s", addr, got, addr)
+	}
+}
+
+func TestTopicToAddrShortTopic(t *testing.T) {
+	// a topic shorter than 40 hex chars isn't a padded address; topicToAddr
+	// should pass it through rather than slicing out of range.
+	if got := topicToAddr("0x01"); got != "0x01" {
+		t.Fatalf("topicToAddr(0x01) = %s, want 0x01 (passthrough)", got)
+	}
+}
+
+// codeWindowLogsAndCode wires a fake JSON-RPC server that answers the two
+// eth_getLogs scans (approval/transfer, discriminated by topics[0]) and the
+// batched eth_getCode lookups scanCodeWindow issues per approval log, in
+// request order: (spender, "latest") then (spender, windowStartTag).
+func newCodeWindowServer(t *testing.T, approvalLogs, transferLogs []rpcLog, codeResults []string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			var reqs []rpcReq
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				t.Fatalf("decode batch request: %v", err)
+			}
+			res := make([]rpcRes, len(reqs))
+			for i, req := range reqs {
+				if i >= len(codeResults) {
+					t.Fatalf("unexpected eth_getCode call %d: %+v", i, req)
+				}
+				res[i] = rpcRes{Jsonrpc: "2.0", Id: req.Id, Result: json.RawMessage(`"` + codeResults[i] + `"`)}
+			}
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+		var req rpcReq
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		var logs []rpcLog
+		if req.Method == "eth_getLogs" {
+			filter := req.Params[0].(map[string]interface{})
+			topics := filter["topics"].([]interface{})
+			switch topics[0].(string) {
+			case topicApproval:
+				logs = approvalLogs
+			case topicTransfer:
+				logs = transferLogs
+			}
+		}
+		raw, err := json.Marshal(logs)
+		if err != nil {
+			t.Fatalf("marshal logs: %v", err)
+		}
+		json.NewEncoder(w).Encode(rpcRes{Jsonrpc: "2.0", Id: req.Id, Result: raw})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestScanCodeWindowFlagsUnlimitedApprovalToFreshSpender(t *testing.T) {
+	addr := "0xabc0000000000000000000000000000000000000"
+	spender := "0xbeef000000000000000000000000000000000000"
+	approvalLogs := []rpcLog{{
+		Address:     addr,
+		Topics:      []string{topicApproval, addrTopic(addr), addrTopic(spender)},
+		Data:        "0x" + maxUint256Hex,
+		BlockNumber: "0x10",
+	}}
+	srv := newCodeWindowServer(t, approvalLogs, nil, []string{
+		"0x6080604052348015600f57600080fd5b50", // code at latest: deployed
+		"0x",                                   // no code at window start: fresh
+	})
+
+	cfg := defaultCodeWindowConfig()
+	score := 0
+	var reasons []string
+	scanCodeWindow(srv.URL, addr, big.NewInt(1000), cfg, &score, &reasons)
+
+	if score != 40 {
+		t.Fatalf("score = %d, want 40 (20 unlimited approval + 20 fresh spender)", score)
+	}
+	if len(reasons) != 2 {
+		t.Fatalf("reasons = %v, want 2 entries", reasons)
+	}
+}
+
+func TestScanCodeWindowIgnoresLimitedApprovalToEstablishedSpender(t *testing.T) {
+	addr := "0xabc0000000000000000000000000000000000000"
+	spender := "0xbeef000000000000000000000000000000000000"
+	approvalLogs := []rpcLog{{
+		Address:     addr,
+		Topics:      []string{topicApproval, addrTopic(addr), addrTopic(spender)},
+		Data:        "0x00000000000000000000000000000000000000000000000000000000000064", // 100, not unlimited
+		BlockNumber: "0x10",
+	}}
+	srv := newCodeWindowServer(t, approvalLogs, nil, []string{
+		"0x6080604052348015600f57600080fd5b50", // code at latest
+		"0x6080604052348015600f57600080fd5b50", // code already present at window start: not fresh
+	})
+
+	cfg := defaultCodeWindowConfig()
+	score := 0
+	var reasons []string
+	scanCodeWindow(srv.URL, addr, big.NewInt(1000), cfg, &score, &reasons)
+
+	if score != 0 {
+		t.Fatalf("score = %d, want 0 for a bounded approval to an established spender", score)
+	}
+	if len(reasons) != 0 {
+		t.Fatalf("reasons = %v, want none", reasons)
+	}
+}
+
+func TestScanCodeWindowFlagsSweepPattern(t *testing.T) {
+	addr := "0xabc0000000000000000000000000000000000000"
+	other := "0xcafe000000000000000000000000000000000000"
+	transferLogs := []rpcLog{
+		{Address: other, Topics: []string{topicTransfer, addrTopic(addr), addrTopic(other)}, BlockNumber: "0x1"},
+		{Address: other, Topics: []string{topicTransfer, addrTopic(addr), addrTopic(other)}, BlockNumber: "0x2"},
+		{Address: other, Topics: []string{topicTransfer, addrTopic(addr), addrTopic(other)}, BlockNumber: "0x3"},
+	}
+	srv := newCodeWindowServer(t, nil, transferLogs, nil)
+
+	cfg := defaultCodeWindowConfig()
+	cfg.SweepTxThresh = 2 // 3 outbound transfers > threshold of 2
+	score := 0
+	var reasons []string
+	scanCodeWindow(srv.URL, addr, big.NewInt(1000), cfg, &score, &reasons)
+
+	if score != 15 {
+		t.Fatalf("score = %d, want 15 (sweep pattern)", score)
+	}
+	if len(reasons) != 1 {
+		t.Fatalf("reasons = %v, want 1 entry", reasons)
+	}
+}
+
+func TestScanCodeWindowIgnoresInboundTransfers(t *testing.T) {
+	addr := "0xabc0000000000000000000000000000000000000"
+	other := "0xcafe000000000000000000000000000000000000"
+	// transfers into addr (topics[1] == other, not addr) shouldn't count as sweeps
+	transferLogs := []rpcLog{
+		{Address: other, Topics: []string{topicTransfer, addrTopic(other), addrTopic(addr)}, BlockNumber: "0x1"},
+		{Address: other, Topics: []string{topicTransfer, addrTopic(other), addrTopic(addr)}, BlockNumber: "0x2"},
+		{Address: other, Topics: []string{topicTransfer, addrTopic(other), addrTopic(addr)}, BlockNumber: "0x3"},
+	}
+	srv := newCodeWindowServer(t, nil, transferLogs, nil)
+
+	cfg := defaultCodeWindowConfig()
+	cfg.SweepTxThresh = 2
+	score := 0
+	var reasons []string
+	scanCodeWindow(srv.URL, addr, big.NewInt(1000), cfg, &score, &reasons)
+
+	if score != 0 {
+		t.Fatalf("score = %d, want 0 for inbound-only transfers", score)
+	}
+}
+
+func TestCodeWindowConfigResolveDefaults(t *testing.T) {
+	cfg := CodeWindowConfig{}.resolve()
+	want := defaultCodeWindowConfig()
+	if cfg != want {
+		t.Fatalf("resolve() of zero-value CodeWindowConfig = %+v, want defaults %+v", cfg, want)
+	}
+}
+
+func TestCodeWindowConfigResolveOverrides(t *testing.T) {
+	cfg := CodeWindowConfig{WindowBlocks: 200, RPCBatchSize: 5, SweepTxThreshold: 3}.resolve()
+	if cfg.WindowBlocks != 200 || cfg.RPCBatchSize != 5 || cfg.SweepTxThresh != 3 {
+		t.Fatalf("resolve() = %+v, want overrides applied", cfg)
+	}
+}
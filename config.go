@@ -0,0 +1,94 @@
+// config.go — daemon config for `server` mode: JSON file describing bind
+// addresses, TLS material, logging, and the set of endpoints/addresses to
+// watch on a timer.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointConfig is one JSON-RPC endpoint, the chain it's expected to
+// serve, and the addresses to watch on it. ChainConfig (multichain.go)
+// carries the chain ID, label, and per-chain thresholds/checkpoint used to
+// sanity-check the endpoint and to scale its heuristics.
+type EndpointConfig struct {
+	ChainConfig
+	Addresses []string `json:"addresses" yaml:"addresses"`
+}
+
+// WebhookConfig describes where to POST a RiskReport when its score crosses
+// Threshold. Kind selects the body shape; "generic" posts the RiskReport
+// JSON as-is, "slack" and "discord" wrap it in the `{"text": "..."}` /
+// `{"content": "..."}` envelope those services expect.
+type WebhookConfig struct {
+	URL  string `json:"url" yaml:"url"`
+	Kind string `json:"kind" yaml:"kind"` // "slack", "discord", or "generic"
+}
+
+// Config is the top-level shape of the file passed to `server -config`.
+type Config struct {
+	GRPCBindAddr string `json:"grpcBindAddr" yaml:"grpcBindAddr"`
+	HTTPBindAddr string `json:"httpBindAddr" yaml:"httpBindAddr"`
+	TLSCertPath  string `json:"tlsCertPath" yaml:"tlsCertPath"`
+	TLSKeyPath   string `json:"tlsKeyPath" yaml:"tlsKeyPath"`
+	TLSSelfSign  bool   `json:"tlsSelfSign" yaml:"tlsSelfSign"` // generate a dev cert if cert/key paths don't exist
+
+	LogLevel string `json:"logLevel" yaml:"logLevel"` // "debug", "info", "warn", "error"
+	LogFile  string `json:"logFile" yaml:"logFile"`   // empty => stderr
+
+	PollIntervalSeconds int `json:"pollIntervalSeconds" yaml:"pollIntervalSeconds"`
+	HistorySize         int `json:"historySize" yaml:"historySize"` // in-memory reports kept per address for diffing
+	Threshold           int `json:"threshold" yaml:"threshold"`     // fire webhook when Score >= Threshold
+
+	StoreBackend string `json:"storeBackend" yaml:"storeBackend"` // "file" (default), "sqlite", "bolt" (see store.go)
+	StorePath    string `json:"storePath" yaml:"storePath"`       // empty => persistent history/z-score baseline/dedup disabled
+
+	Endpoints []EndpointConfig `json:"endpoints" yaml:"endpoints"`
+	Webhook   WebhookConfig    `json:"webhook" yaml:"webhook"`
+}
+
+// PollInterval returns the configured poll interval, defaulting to 60s.
+func (c Config) PollInterval() time.Duration {
+	if c.PollIntervalSeconds <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.PollIntervalSeconds) * time.Second
+}
+
+// LoadConfig reads and parses a config file, either JSON or YAML: a
+// ".yaml"/".yml" extension is parsed as YAML, everything else (including no
+// extension) as JSON. Every field carries an explicit `yaml:` tag matching
+// its `json:` tag, so the two formats use identical field names.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	default:
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("parse config: %w", err)
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 20
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 60
+	}
+	if len(cfg.Endpoints) == 0 {
+		return cfg, fmt.Errorf("config has no endpoints")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,182 @@
+// grpc.go — the ProbeService gRPC surface: Probe (unary) and Watch (server
+// streaming), served alongside serveHTTP's REST/JSON handlers on
+// cfg.GRPCBindAddr.
+//
+// There is no protoc binary in this environment (only the Go module proxy is
+// reachable — apt has no network access here), so there is no .proto file
+// and no protoc-gen-go/protoc-gen-go-grpc generated pb.go pair. What follows
+// is the hand-written equivalent of that generated code: a real
+// grpc.ServiceDesc, real unary/server-stream handler shims, served by a real
+// grpc.Server over HTTP/2 — the only difference from a protoc-generated
+// service is the wire codec. jsonCodec below registers an encoding/json
+// codec under the "proto" name (the content-subtype grpc-go negotiates by
+// default), so messages go over the wire as JSON instead of protobuf binary.
+// Swapping in generated protobuf types later is a drop-in replacement: keep
+// this ServiceDesc and handlers, add a real .proto-generated jsonCodec-free
+// build once protoc is available.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// jsonCodec implements grpc/encoding.Codec using encoding/json. Registered
+// under Name() "proto" because that's the content-subtype grpc-go clients
+// request by default; see the package comment above.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "proto" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// ProbeRequest is the Probe/Watch RPC request: the address to probe.
+type ProbeRequest struct {
+	Address string `json:"address"`
+}
+
+// WatchRequest is a Probe request plus an optional interval override;
+// IntervalSeconds <= 0 means use the daemon's configured PollInterval.
+type WatchRequest struct {
+	Address         string `json:"address"`
+	IntervalSeconds int    `json:"intervalSeconds"`
+}
+
+// ProbeServiceServer is the server API for ProbeService.
+type ProbeServiceServer interface {
+	Probe(context.Context, *ProbeRequest) (*RiskReport, error)
+	Watch(*WatchRequest, ProbeService_WatchServer) error
+}
+
+// ProbeService_WatchServer is the server-side stream handle Watch sends
+// RiskReports on.
+type ProbeService_WatchServer interface {
+	Send(*RiskReport) error
+	grpc.ServerStream
+}
+
+type probeServiceWatchServer struct{ grpc.ServerStream }
+
+func (x *probeServiceWatchServer) Send(m *RiskReport) error { return x.ServerStream.SendMsg(m) }
+
+func _ProbeService_Probe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).Probe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/secureprobe.ProbeService/Probe"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).Probe(ctx, req.(*ProbeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProbeServiceServer).Watch(m, &probeServiceWatchServer{stream})
+}
+
+var probeServiceDesc = grpc.ServiceDesc{
+	ServiceName: "secureprobe.ProbeService",
+	HandlerType: (*ProbeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Probe", Handler: _ProbeService_Probe_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Watch", Handler: _ProbeService_Watch_Handler, ServerStreams: true},
+	},
+	Metadata: "probeservice.proto",
+}
+
+// grpcProbeServer implements ProbeServiceServer against the running daemon,
+// sharing endpointFor/recordReport with serveHTTP's /probe handler.
+type grpcProbeServer struct {
+	s *server
+}
+
+func (g *grpcProbeServer) Probe(ctx context.Context, req *ProbeRequest) (*RiskReport, error) {
+	ep, ok := g.s.endpointFor(req.Address)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "address %s not configured", req.Address)
+	}
+	history := g.s.historyFor(ep.ChainID, req.Address)
+	report, err := probeOnceWithThresholds(ep.RPCURL, req.Address, ep.Thresholds, history)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "probe failed: %v", err)
+	}
+	g.s.recordReport(req.Address, report)
+	return &report, nil
+}
+
+func (g *grpcProbeServer) Watch(req *WatchRequest, stream ProbeService_WatchServer) error {
+	ep, ok := g.s.endpointFor(req.Address)
+	if !ok {
+		return status.Errorf(codes.NotFound, "address %s not configured", req.Address)
+	}
+	interval := g.s.cfg.PollInterval()
+	if req.IntervalSeconds > 0 {
+		interval = time.Duration(req.IntervalSeconds) * time.Second
+	}
+	ctx := stream.Context()
+	for {
+		history := g.s.historyFor(ep.ChainID, req.Address)
+		report, err := probeOnceWithThresholds(ep.RPCURL, req.Address, ep.Thresholds, history)
+		if err != nil {
+			return status.Errorf(codes.Internal, "probe failed: %v", err)
+		}
+		g.s.recordReport(req.Address, report)
+		if err := stream.Send(&report); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// serveGRPC listens on cfg.GRPCBindAddr and serves ProbeService until the
+// listener fails. Called from a goroutine in runServer alongside serveHTTP.
+// Shares serveHTTP's TLS config (TLSCertPath/TLSKeyPath/TLSSelfSign): the
+// same RiskReports stream over both surfaces, so gRPC gets the same
+// transport security rather than silently falling back to plaintext.
+// runServer ensures the self-signed cert/key exist before either server
+// starts.
+func (s *server) serveGRPC() error {
+	lis, err := net.Listen("tcp", s.cfg.GRPCBindAddr)
+	if err != nil {
+		return fmt.Errorf("grpc listen: %w", err)
+	}
+	var opts []grpc.ServerOption
+	if s.cfg.TLSCertPath != "" || s.cfg.TLSSelfSign {
+		creds, err := credentials.NewServerTLSFromFile(s.cfg.TLSCertPath, s.cfg.TLSKeyPath)
+		if err != nil {
+			return fmt.Errorf("grpc tls: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	grpcServer.RegisterService(&probeServiceDesc, &grpcProbeServer{s: s})
+	s.log.Infof("grpc ProbeService listening addr=%s tls=%v", s.cfg.GRPCBindAddr, len(opts) > 0)
+	return grpcServer.Serve(lis)
+}
@@ -0,0 +1,177 @@
+// multichain.go — run the probe across several EVM chains at once, each
+// with its own RPC endpoint and alert thresholds, refusing to trust an
+// endpoint whose eth_chainId or trusted checkpoint block hash doesn't
+// match what's configured (catches misrouted or malicious RPCs before
+// any of their other answers are trusted).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ChainThresholds parameterizes probeOnceWithThresholds per chain, since a
+// "1 ETH" balance drop or a 20-nonce/100-block burst means something
+// different on Polygon (MATIC) or BSC (BNB) than on Ethereum mainnet.
+type ChainThresholds struct {
+	GasDecimals    int              `json:"gasDecimals" yaml:"gasDecimals"`       // native gas token decimals; 0 => 18
+	NonceRateAlert int64            `json:"nonceRateAlert" yaml:"nonceRateAlert"` // nonce increase per 100 blocks that scores; 0 => 20
+	CodeWindow     CodeWindowConfig `json:"codeWindow" yaml:"codeWindow"`         // window size, RPC batch size, sweep threshold (codewindow.go)
+}
+
+func (t ChainThresholds) gasDecimals() int {
+	if t.GasDecimals <= 0 {
+		return 18
+	}
+	return t.GasDecimals
+}
+
+func (t ChainThresholds) nonceRateAlert() int64 {
+	if t.NonceRateAlert <= 0 {
+		return 20
+	}
+	return t.NonceRateAlert
+}
+
+// Checkpoint is a known-good (block number, block hash) pair for a chain.
+// Checked at startup against eth_getBlockByNumber on the configured RPC;
+// a mismatch means the endpoint is forked or lying.
+type Checkpoint struct {
+	BlockNumber int64  `json:"blockNumber" yaml:"blockNumber"`
+	BlockHash   string `json:"blockHash" yaml:"blockHash"`
+}
+
+// defaultCheckpoints is a small embedded table of known-good anchor points,
+// keyed by chain ID, used when a ChainConfig doesn't supply its own
+// Checkpoint. A ChainConfig.Checkpoint always takes precedence over this
+// table (see resolveCheckpoint), so operators can override or supply their
+// own for any chain.
+//
+// Only Ethereum mainnet (chain ID 1) is seeded here, with its genesis block
+// - the one anchor point that doesn't drift and is independently verifiable
+// by anyone. Sepolia, Holesky, BSC, Polygon, Arbitrum, Optimism, and Base
+// are deliberately left unseeded: a wrong hardcoded hash would silently
+// misclassify a legitimate RPC as untrusted, which is worse than not
+// checking at all. Operators targeting those chains should supply their own
+// `checkpoint` in config (a recent finalized block from a source they
+// trust); verifyChain skips the checkpoint check entirely when none is
+// available, same as before this table existed.
+var defaultCheckpoints = map[int64]Checkpoint{
+	1: {BlockNumber: 0, BlockHash: "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa"}, // Ethereum mainnet genesis
+}
+
+// resolveCheckpoint returns cfg's own checkpoint if set, else the default
+// table entry for cfg.ChainID, else nil (no checkpoint check performed).
+func resolveCheckpoint(cfg ChainConfig) *Checkpoint {
+	if cfg.Checkpoint != nil {
+		return cfg.Checkpoint
+	}
+	if cp, ok := defaultCheckpoints[cfg.ChainID]; ok {
+		return &cp
+	}
+	return nil
+}
+
+// ChainConfig is one chain's worth of probing config: its expected chain
+// ID, a human label, its RPC endpoint, alert thresholds, and an optional
+// trusted checkpoint.
+type ChainConfig struct {
+	ChainID    int64           `json:"chainId" yaml:"chainId"`
+	Label      string          `json:"label" yaml:"label"`
+	RPCURL     string          `json:"rpcUrl" yaml:"rpcUrl"`
+	WSURL      string          `json:"wsUrl" yaml:"wsUrl"` // optional ws(s):// companion to RPCURL for real-time newHeads-driven polling (server.go's wsPollLoop); empty => poll on a fixed timer
+	Thresholds ChainThresholds `json:"thresholds" yaml:"thresholds"`
+	Checkpoint *Checkpoint     `json:"checkpoint" yaml:"checkpoint"`
+}
+
+// ChainReport wraps one chain's RiskReport with the chain metadata it came
+// from and whether the endpoint failed its chain-ID/checkpoint sanity
+// checks (in which case Report is zero-valued and should not be trusted).
+type ChainReport struct {
+	ChainID   int64      `json:"chainId"`
+	Label     string     `json:"label"`
+	Untrusted bool       `json:"untrusted"`
+	Reason    string     `json:"reason,omitempty"`
+	Report    RiskReport `json:"report"`
+}
+
+// CombinedReport aggregates one ChainReport per configured chain plus a
+// top-level score: the worst single-chain score, since a drain on any one
+// chain is as urgent as a drain on all of them.
+type CombinedReport struct {
+	Chains        []ChainReport `json:"chains"`
+	CombinedScore int           `json:"combinedScore"`
+}
+
+// ProbeAllChains verifies each configured chain's endpoint, then probes
+// addr on every chain that passes verification. It has no store to draw a
+// per-address baseline from, so each chain's balance-drop heuristic runs in
+// probeOnceWithThresholds's no-history fallback mode (see its doc comment).
+func ProbeAllChains(chains []ChainConfig, addr string) CombinedReport {
+	var combined CombinedReport
+	for _, cfg := range chains {
+		untrusted, reason := verifyChain(cfg)
+		cr := ChainReport{ChainID: cfg.ChainID, Label: cfg.Label, Untrusted: untrusted, Reason: reason}
+		if untrusted {
+			cr.Report.Score = 15
+			cr.Report.Reasons = []string{reason}
+		} else {
+			report, err := probeOnceWithThresholds(cfg.RPCURL, addr, cfg.Thresholds, nil)
+			if err != nil {
+				cr.Untrusted = true
+				cr.Reason = fmt.Sprintf("probe failed: %v", err)
+			} else {
+				cr.Report = report
+			}
+		}
+		if cr.Report.Score > combined.CombinedScore {
+			combined.CombinedScore = cr.Report.Score
+		}
+		combined.Chains = append(combined.Chains, cr)
+	}
+	return combined
+}
+
+// verifyChain refuses to trust an RPC endpoint that disagrees with its
+// configured chain ID, or whose checkpoint block hash doesn't match the
+// configured trusted value.
+func verifyChain(cfg ChainConfig) (untrusted bool, reason string) {
+	client := clientFor(cfg.RPCURL)
+	ctx := context.Background()
+
+	idRaw, err := client.Call(ctx, "eth_chainId")
+	if err != nil {
+		return true, fmt.Sprintf("eth_chainId failed: %v", err)
+	}
+	idBig, err := hexBigFromRaw(idRaw)
+	if err != nil {
+		return true, fmt.Sprintf("eth_chainId: %v", err)
+	}
+	gotID := idBig.Int64()
+	if gotID != cfg.ChainID {
+		return true, fmt.Sprintf("rpc reports chainId %d, expected %d", gotID, cfg.ChainID)
+	}
+
+	checkpoint := resolveCheckpoint(cfg)
+	if checkpoint == nil {
+		return false, ""
+	}
+	tag := fmt.Sprintf("0x%x", big.NewInt(checkpoint.BlockNumber))
+	blockRaw, err := client.Call(ctx, "eth_getBlockByNumber", tag, false)
+	if err != nil {
+		return true, fmt.Sprintf("checkpoint fetch failed: %v", err)
+	}
+	var block struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(blockRaw, &block); err != nil {
+		return true, fmt.Sprintf("checkpoint decode failed: %v", err)
+	}
+	if !strings.EqualFold(block.Hash, checkpoint.BlockHash) {
+		return true, "rpc returned wrong hash at checkpoint"
+	}
+	return false, ""
+}
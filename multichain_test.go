@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveCheckpointPrefersConfigOverDefaultTable(t *testing.T) {
+	own := &Checkpoint{BlockNumber: 99, BlockHash: "0xcustom"}
+	cfg := ChainConfig{ChainID: 1, Checkpoint: own} // chain 1 also has a default table entry
+	got := resolveCheckpoint(cfg)
+	if got != own {
+		t.Fatalf("resolveCheckpoint = %+v, want the configured checkpoint %+v", got, own)
+	}
+}
+
+func TestResolveCheckpointFallsBackToDefaultTable(t *testing.T) {
+	cfg := ChainConfig{ChainID: 1}
+	got := resolveCheckpoint(cfg)
+	if got == nil || got.BlockHash != defaultCheckpoints[1].BlockHash {
+		t.Fatalf("resolveCheckpoint = %v, want the default mainnet genesis checkpoint", got)
+	}
+}
+
+func TestResolveCheckpointNilForUnknownChainWithNoConfig(t *testing.T) {
+	cfg := ChainConfig{ChainID: 999999}
+	if got := resolveCheckpoint(cfg); got != nil {
+		t.Fatalf("resolveCheckpoint = %+v, want nil for an unseeded chain with no configured checkpoint", got)
+	}
+}
+
+// newChainVerifyServer answers eth_chainId with chainIDResult (already
+// JSON-encoded, e.g. `"0x1"` or a malformed payload) and eth_getBlockByNumber
+// with a block whose hash is blockHash.
+func newChainVerifyServer(t *testing.T, chainIDResult json.RawMessage, blockHash string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		body = bytes.TrimSpace(body)
+		var req rpcReq
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		var result json.RawMessage
+		switch req.Method {
+		case "eth_chainId":
+			result = chainIDResult
+		case "eth_getBlockByNumber":
+			block, err := json.Marshal(struct {
+				Hash string `json:"hash"`
+			}{Hash: blockHash})
+			if err != nil {
+				t.Fatalf("marshal block: %v", err)
+			}
+			result = block
+		}
+		json.NewEncoder(w).Encode(rpcRes{Jsonrpc: "2.0", Id: req.Id, Result: result})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVerifyChainChainIDMismatch(t *testing.T) {
+	srv := newChainVerifyServer(t, json.RawMessage(`"0x2"`), "")
+	cfg := ChainConfig{ChainID: 1, RPCURL: srv.URL}
+
+	untrusted, reason := verifyChain(cfg)
+	if !untrusted {
+		t.Fatalf("untrusted = false, want true for a chainId mismatch (reason: %s)", reason)
+	}
+}
+
+// TestVerifyChainMalformedChainIDDoesNotPanic covers the exact failure mode
+// a misbehaving or malicious RPC can trigger: a well-formed-JSON but
+// non-quoted eth_chainId result (e.g. a bare number or boolean) used to slice
+// out of range instead of being refused.
+func TestVerifyChainMalformedChainIDDoesNotPanic(t *testing.T) {
+	for _, result := range []json.RawMessage{
+		json.RawMessage(`0`),
+		json.RawMessage(`true`),
+		json.RawMessage(`1`),
+	} {
+		srv := newChainVerifyServer(t, result, "")
+		cfg := ChainConfig{ChainID: 1, RPCURL: srv.URL}
+
+		untrusted, reason := verifyChain(cfg)
+		if !untrusted {
+			t.Fatalf("untrusted = false, want true for malformed eth_chainId result %s", result)
+		}
+		if reason == "" {
+			t.Fatalf("reason = %q, want a non-empty explanation for malformed eth_chainId result %s", reason, result)
+		}
+	}
+}
+
+func TestVerifyChainCheckpointHashMismatch(t *testing.T) {
+	srv := newChainVerifyServer(t, json.RawMessage(`"0x1"`), "0xwronghash")
+	cfg := ChainConfig{
+		ChainID: 1, RPCURL: srv.URL,
+		Checkpoint: &Checkpoint{BlockNumber: 0, BlockHash: "0xexpectedhash"},
+	}
+
+	untrusted, reason := verifyChain(cfg)
+	if !untrusted {
+		t.Fatalf("untrusted = false, want true for a checkpoint hash mismatch (reason: %s)", reason)
+	}
+}
+
+func TestVerifyChainCheckpointHashMatchIsCaseInsensitive(t *testing.T) {
+	srv := newChainVerifyServer(t, json.RawMessage(`"0x1"`), "0xABCDEF")
+	cfg := ChainConfig{
+		ChainID: 1, RPCURL: srv.URL,
+		Checkpoint: &Checkpoint{BlockNumber: 0, BlockHash: "0xabcdef"},
+	}
+
+	untrusted, reason := verifyChain(cfg)
+	if untrusted {
+		t.Fatalf("untrusted = true (reason: %s), want false for a matching checkpoint hash differing only in case", reason)
+	}
+}
+
+func TestVerifyChainSkipsCheckpointWhenNoneConfigured(t *testing.T) {
+	srv := newChainVerifyServer(t, json.RawMessage(`"0x2a"`), "")
+	cfg := ChainConfig{ChainID: 42, RPCURL: srv.URL} // unseeded chain, no Checkpoint set
+
+	untrusted, reason := verifyChain(cfg)
+	if untrusted {
+		t.Fatalf("untrusted = true (reason: %s), want false when no checkpoint applies", reason)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTxScanServer answers eth_getBlockByNumber for tag with a block whose
+// baseFeePerGas is baseFee and whose transactions are txs, and answers
+// eth_getRawTransactionByHash for each hash in rawByHash with the
+// corresponding raw RLP hex (or a deliberately malformed value, to exercise
+// ScanRecentTxs's decode-failure path).
+func newTxScanServer(t *testing.T, tag, baseFee string, txs []struct{ hash, from string }, rawByHash map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req rpcReq
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		var result json.RawMessage
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			if req.Params[0].(string) != tag {
+				t.Fatalf("eth_getBlockByNumber tag = %v, want %s", req.Params[0], tag)
+			}
+			block, err := json.Marshal(struct {
+				BaseFeePerGas string `json:"baseFeePerGas"`
+				Transactions  []struct {
+					Hash string `json:"hash"`
+					From string `json:"from"`
+				} `json:"transactions"`
+			}{
+				BaseFeePerGas: baseFee,
+				Transactions: func() (out []struct {
+					Hash string `json:"hash"`
+					From string `json:"from"`
+				}) {
+					for _, tx := range txs {
+						out = append(out, struct {
+							Hash string `json:"hash"`
+							From string `json:"from"`
+						}{Hash: tx.hash, From: tx.from})
+					}
+					return out
+				}(),
+			})
+			if err != nil {
+				t.Fatalf("marshal block: %v", err)
+			}
+			result = block
+		case "eth_getRawTransactionByHash":
+			hash := req.Params[0].(string)
+			rawHex, ok := rawByHash[hash]
+			if !ok {
+				t.Fatalf("unexpected eth_getRawTransactionByHash for %s", hash)
+			}
+			rawResult, err := json.Marshal(rawHex)
+			if err != nil {
+				t.Fatalf("marshal raw tx: %v", err)
+			}
+			result = rawResult
+		default:
+			t.Fatalf("unexpected method %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(rpcRes{Jsonrpc: "2.0", Id: req.Id, Result: result})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// legacyTxHex builds a minimal legacy-tx RLP payload (using the same
+// encoder helpers as the decoder tests) with the given `to` address and
+// gasPrice, for use as a ScanRecentTxs fixture.
+func legacyTxHex(to []byte, gasPrice int64) string {
+	raw := rlpEncList(
+		rlpEncStr([]byte{0x01}),                                                   // nonce
+		rlpEncStr(big.NewInt(gasPrice).Bytes()),                                   // gasPrice
+		rlpEncStr([]byte{0x52, 0x08}),                                             // gas
+		rlpEncStr(to),                                                             // to
+		rlpEncStr([]byte{0x0a}),                                                   // value
+		rlpEncStr(nil),                                                            // data
+		rlpEncStr([]byte{0x1b}), rlpEncStr([]byte{0x01}), rlpEncStr([]byte{0x02}), // v, r, s
+	)
+	return "0x" + hex.EncodeToString(raw)
+}
+
+func TestScanRecentTxsClassifiesMatchedTxAndSkipsOtherSenders(t *testing.T) {
+	const addr = "0xabc0000000000000000000000000000000000000"
+	const other = "0xbeef000000000000000000000000000000000000"
+	dest := addrBytes(0x11)
+
+	txs := []struct{ hash, from string }{
+		{"0xhash1", addr},
+		{"0xhash2", other}, // different sender: must not be scanned
+	}
+	raw := map[string]string{
+		"0xhash1": legacyTxHex(dest, 100), // gasPrice 100 > 3x baseFee 1
+	}
+	srv := newTxScanServer(t, "0x64", "0x1", txs, raw)
+
+	score, reasons := ScanRecentTxs(srv.URL, addr, big.NewInt(100), 1)
+
+	if score < 10 {
+		t.Fatalf("score = %d, want >= 10 for a sweeper-premium gas price on the matched tx", score)
+	}
+	found := false
+	for _, r := range reasons {
+		if strings.Contains(r, "0xhash1") && strings.Contains(r, "sweeper front-run premium") {
+			found = true
+		}
+		if strings.Contains(r, "0xhash2") {
+			t.Fatalf("reasons mention 0xhash2, which was sent by a different address: %v", reasons)
+		}
+	}
+	if !found {
+		t.Fatalf("reasons = %v, want one classifying 0xhash1's gas price", reasons)
+	}
+}
+
+func TestScanRecentTxsFlagsMultiDestinationSweepBatching(t *testing.T) {
+	const addr = "0xabc0000000000000000000000000000000000000"
+	destA, destB := addrBytes(0x11), addrBytes(0x22)
+
+	txs := []struct{ hash, from string }{
+		{"0xhash1", addr},
+		{"0xhash2", addr},
+	}
+	raw := map[string]string{
+		"0xhash1": legacyTxHex(destA, 1),
+		"0xhash2": legacyTxHex(destB, 1),
+	}
+	srv := newTxScanServer(t, "0x64", "0x1", txs, raw)
+
+	score, reasons := ScanRecentTxs(srv.URL, addr, big.NewInt(100), 1)
+
+	if score < 10 {
+		t.Fatalf("score = %d, want >= 10 for 2 distinct destinations in one block", score)
+	}
+	found := false
+	for _, r := range reasons {
+		if strings.Contains(r, "distinct destinations") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("reasons = %v, want a sweep-batching reason", reasons)
+	}
+}
+
+func TestScanRecentTxsSurfacesDecodeFailureReason(t *testing.T) {
+	const addr = "0xabc0000000000000000000000000000000000000"
+
+	txs := []struct{ hash, from string }{
+		{"0xhash1", addr},
+	}
+	raw := map[string]string{
+		"0xhash1": "0xnotvalidhex",
+	}
+	srv := newTxScanServer(t, "0x64", "0x1", txs, raw)
+
+	score, reasons := ScanRecentTxs(srv.URL, addr, big.NewInt(100), 1)
+
+	if score != 0 {
+		t.Fatalf("score = %d, want 0 for a tx that failed to decode", score)
+	}
+	if len(reasons) != 1 || !strings.Contains(reasons[0], "0xhash1") || !strings.Contains(reasons[0], "decode failed") {
+		t.Fatalf("reasons = %v, want exactly one decode-failure reason for 0xhash1", reasons)
+	}
+}
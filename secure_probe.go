@@ -4,14 +4,12 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"net/http"
 	"os"
 	"strings"
-	"time"
 )
 
 type rpcReq struct {
@@ -22,6 +20,7 @@ type rpcReq struct {
 }
 type rpcRes struct {
 	Jsonrpc string          `json:"jsonrpc"`
+	Id      int             `json:"id"`
 	Result  json.RawMessage `json:"result"`
 	Error   *struct {
 		Code    int    `json:"code"`
@@ -29,22 +28,10 @@ type rpcRes struct {
 	} `json:"error,omitempty"`
 }
 
-func call(url, method string, params ...interface{}) (json.RawMessage, error) {
-	payload, _ := json.Marshal(rpcReq{"2.0", method, params, 1})
-	resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var out rpcRes
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, err
-	}
-	if out.Error != nil {
-		return nil, fmt.Errorf("rpc error %d: %s", out.Error.Code, out.Error.Message)
-	}
-	return out.Result, nil
-}
+// call() itself now lives in client.go: a one-shot http.Post per call was
+// replaced with a retrying, batching Client. call() remains as a
+// convenience wrapper over a cached Client for call sites below that don't
+// need context cancellation or batching.
 
 func hexToBig(s string) *big.Int {
 	z := new(big.Int)
@@ -52,85 +39,191 @@ func hexToBig(s string) *big.Int {
 	return z
 }
 
-func weiToEth(x *big.Int) *big.Float {
+// hexBigOrZero unwraps a quoted hex-string BatchResult, returning zero if
+// the call errored or returned something unparseable.
+func hexBigOrZero(r BatchResult) *big.Int {
+	if r.Err != nil || len(r.Result) < 2 {
+		return new(big.Int)
+	}
+	return hexToBig(string(r.Result[1 : len(r.Result)-1]))
+}
+
+// hexBigFromRaw unwraps a quoted hex-string RPC result, e.g. the raw
+// json.RawMessage returned by Client.Call for eth_blockNumber or
+// eth_chainId. Unlike hexBigOrZero it errors instead of silently
+// returning zero: callers here (probeOnceWithThresholds, verifyChain) use
+// the result to decide which block to probe or whether to trust an
+// endpoint at all, so a malformed (too short, unquoted, non-string)
+// result from a misbehaving RPC must surface as an error rather than
+// panic on the slice bounds or silently probe block 0.
+func hexBigFromRaw(raw json.RawMessage) (*big.Int, error) {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return nil, fmt.Errorf("expected quoted hex string, got %q", raw)
+	}
+	return hexToBig(string(raw[1 : len(raw)-1])), nil
+}
+
+// weiToToken converts a smallest-unit integer amount to a decimal amount
+// of the native gas token, given that token's decimals (18 for ETH/BNB/
+// MATIC, but configurable per chain — see ChainThresholds in multichain.go).
+func weiToToken(x *big.Int, decimals int) *big.Float {
 	f := new(big.Float).SetInt(x)
-	den := big.NewFloat(1e18)
+	den := new(big.Float).SetFloat64(1)
+	ten := big.NewFloat(10)
+	for i := 0; i < decimals; i++ {
+		den.Mul(den, ten)
+	}
 	f.Quo(f, den)
 	return f
 }
 
-func main() {
-	rpc := os.Getenv("RPC_URL")
-	addr := os.Getenv("ADDRESS")
-	if rpc == "" || addr == "" {
-		fmt.Println(`usage: RPC_URL=<rpc> ADDRESS=<0x..> ./secure-probe-go`)
-		os.Exit(1)
-	}
-	// latest block number
-	bnRaw, err := call(rpc, "eth_blockNumber")
-	if err != nil { panic(err) }
-	latest := hexToBig(string(bnRaw[1:len(bnRaw)-1]))
+func weiToEth(x *big.Int) *big.Float {
+	return weiToToken(x, 18)
+}
+
+// RiskReport is the result of one probe of one address: a score in [0..100]
+// plus the human-readable reasons that produced it. It is shared by the
+// one-shot CLI, the poll loop in server.go, and (eventually) the wire types
+// for the RPC surfaces described in server.go.
+type RiskReport struct {
+	Address       string   `json:"address"`
+	Block         string   `json:"latestBlock"`
+	Score         int      `json:"riskScore"`
+	Reasons       []string `json:"reasons"`
+	BalanceEth    string   `json:"balanceEth"`
+	BalanceWei    string   `json:"balanceWei"`    // raw balance, for callers (e.g. store.go) that need precision
+	WindowDropWei string   `json:"windowDropWei"` // raw 100-block-window balance drop (wei, positive => drained); persisted so outflowZScore can baseline same-timescale samples, see store.go
+	Nonce         int64    `json:"nonce"`
+}
 
-	// balance at latest and 100 blocks ago (if possible)
-	balLatestRaw, _ := call(rpc, "eth_getBalance", addr, "latest")
-	balLatest := hexToBig(string(balLatestRaw[1:len(balLatestRaw)-1]))
+// probeOnce runs the current heuristics for addr against rpc using the
+// Ethereum-mainnet defaults and returns the resulting RiskReport. Factored
+// out of main so the server subcommand can call it on a timer per
+// configured address. See probeOnceWithThresholds for the per-chain
+// variant used by multichain.go. Since the one-shot CLI has no store, it
+// has no baseline history to z-score against (see probeOnceWithThresholds).
+func probeOnce(rpc, addr string) (RiskReport, error) {
+	return probeOnceWithThresholds(rpc, addr, ChainThresholds{}, nil)
+}
+
+// probeOnceWithThresholds is probeOnce generalized to chain-specific
+// thresholds: a "1 ETH" balance drop or a 20-nonce/100-block burst doesn't
+// mean the same thing on every chain (BSC, Polygon, etc.), so th lets
+// multichain.go parameterize both per configured chain. history is this
+// address's prior persisted cycles (oldest first, from store.go), used to
+// judge the current balance drop by z-score against its own baseline rather
+// than by a flat "any decrease" rule; pass nil when no store is configured
+// (CLI one-shot mode, multichain probing) and the balance-drop heuristic
+// falls back to a lower-severity flat check instead.
+func probeOnceWithThresholds(rpc, addr string, th ChainThresholds, history []StoreRecord) (RiskReport, error) {
+	ctx := context.Background()
+	client := clientFor(rpc)
+
+	// latest block number
+	bnRaw, err := client.Call(ctx, "eth_blockNumber")
+	if err != nil {
+		return RiskReport{}, err
+	}
+	latest, err := hexBigFromRaw(bnRaw)
+	if err != nil {
+		return RiskReport{}, fmt.Errorf("eth_blockNumber: %w", err)
+	}
 
-	var balPast *big.Int = new(big.Int).Set(balLatest)
-	var pastBlock *big.Int = new(big.Int).Set(latest)
+	pastBlock := new(big.Int).Set(latest)
 	if latest.Cmp(big.NewInt(100)) > 0 {
 		pastBlock.Sub(latest, big.NewInt(100))
-		tag := fmt.Sprintf("0x%x", pastBlock)
-		balPastRaw, _ := call(rpc, "eth_getBalance", addr, tag)
-		balPast = hexToBig(string(balPastRaw[1:len(balPastRaw)-1]))
 	}
+	pastTag := fmt.Sprintf("0x%x", pastBlock)
 
-	// nonce now vs 100 blocks ago
-	nonceNowRaw, _ := call(rpc, "eth_getTransactionCount", addr, "latest")
-	noncePastRaw, _ := call(rpc, "eth_getTransactionCount", addr, fmt.Sprintf("0x%x", pastBlock))
-	nonceNow := hexToBig(string(nonceNowRaw[1:len(nonceNowRaw)-1]))
-	noncePast := hexToBig(string(noncePastRaw[1:len(noncePastRaw)-1]))
+	// balance/nonce/code at latest and 100 blocks ago, batched into one
+	// HTTP round trip instead of 5 separate calls.
+	results, err := client.BatchCall(ctx,
+		RPCCall{Method: "eth_getBalance", Params: []interface{}{addr, "latest"}},
+		RPCCall{Method: "eth_getBalance", Params: []interface{}{addr, pastTag}},
+		RPCCall{Method: "eth_getTransactionCount", Params: []interface{}{addr, "latest"}},
+		RPCCall{Method: "eth_getTransactionCount", Params: []interface{}{addr, pastTag}},
+		RPCCall{Method: "eth_getCode", Params: []interface{}{addr, "latest"}},
+	)
+	if err != nil {
+		return RiskReport{}, err
+	}
+	balLatest := hexBigOrZero(results[0])
+	balPast := hexBigOrZero(results[1])
+	nonceNow := hexBigOrZero(results[2])
+	noncePast := hexBigOrZero(results[3])
+	codeNowRaw := results[4].Result
 
 	// simple heuristics
 	score := 0
 	reasons := []string{}
 	balDiff := new(big.Int).Sub(balPast, balLatest) // positive => drained
-	if balDiff.Sign() > 0 {
-		score += 35
-		reasons = append(reasons, fmt.Sprintf("balance drop ~%s ETH/100 blocks", weiToEth(balDiff).Text('f', 6)))
+	dropStr := weiToToken(balDiff, th.gasDecimals()).Text('f', 6)
+	if z, ok := outflowZScore(history, new(big.Float).SetInt(balDiff)); ok {
+		if z > outflowZScoreAlert {
+			score += 35
+			reasons = append(reasons, fmt.Sprintf("balance drop ~%s/100 blocks is %.1f standard deviations above this address's baseline", dropStr, z))
+		}
+	} else if balDiff.Sign() > 0 {
+		// No baseline yet (first few cycles, or a caller with no store at
+		// all - CLI one-shot mode, ProbeAllChains) to z-score against: fall
+		// back to the old flat "any decrease" signal, at reduced severity
+		// since it hasn't been checked against this address's own history.
+		score += 15
+		reasons = append(reasons, fmt.Sprintf("balance drop ~%s/100 blocks (no baseline yet to z-score against)", dropStr))
 	}
 	nonceDiff := new(big.Int).Sub(nonceNow, noncePast)
-	if nonceDiff.Cmp(big.NewInt(20)) > 0 {
+	if nonceDiff.Cmp(big.NewInt(th.nonceRateAlert())) > 0 {
 		score += 25
 		reasons = append(reasons, fmt.Sprintf("high tx activity: +%s nonce/100 blocks", nonceDiff.String()))
 	}
 
-	// check if code recently deployed at nearby blocks (potential approval phishing)
-	type codeWindow struct{ Start, End *big.Int }
-	w := codeWindow{new(big.Int).Sub(latest, big.NewInt(50)), latest}
-	probeAddr := addr // naive: test code presence at the address (AA smart wallets)
-	codeNowRaw, _ := call(rpc, "eth_getCode", probeAddr, "latest")
+	// code presence at the address itself (AA smart wallets), from the batch above
 	if len(codeNowRaw) > 4 { // not "0x"
 		score += 10
 		reasons = append(reasons, "address has code (smart wallet or contract)")
 	}
-	_ = w // reserved for extension: scan logs in window
-
-	if score > 100 { score = 100 }
-	out := struct {
-		Address   string   `json:"address"`
-		Block     string   `json:"latestBlock"`
-		Score     int      `json:"riskScore"`
-		Reasons   []string `json:"reasons"`
-		BalLatest string   `json:"balanceEth"`
-	}{
-		Address: addr,
-		Block:   fmt.Sprintf("0x%x", latest),
-		Score:   score,
-		Reasons: reasons,
-		BalLatest: weiToEth(balLatest).Text('f', 6),
+	scanCodeWindow(rpc, addr, latest, th.CodeWindow.resolve(), &score, &reasons)
+
+	txScore, txReasons := ScanRecentTxs(rpc, addr, latest, defaultTxScanBlocks)
+	score += txScore
+	reasons = append(reasons, txReasons...)
+
+	if score > 100 {
+		score = 100
+	}
+	return RiskReport{
+		Address:       addr,
+		Block:         fmt.Sprintf("0x%x", latest),
+		Score:         score,
+		Reasons:       reasons,
+		BalanceEth:    weiToToken(balLatest, th.gasDecimals()).Text('f', 6),
+		BalanceWei:    balLatest.String(),
+		WindowDropWei: balDiff.String(),
+		Nonce:         nonceNow.Int64(),
+	}, nil
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		if err := runServer(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "server:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	rpc := os.Getenv("RPC_URL")
+	addr := os.Getenv("ADDRESS")
+	if rpc == "" || addr == "" {
+		fmt.Println(`usage: RPC_URL=<rpc> ADDRESS=<0x..> ./secure-probe-go
+       ./secure-probe-go server -config probe.json`)
+		os.Exit(1)
+	}
+	out, err := probeOnce(rpc, addr)
+	if err != nil {
+		panic(err)
 	}
 	enc, _ := json.MarshalIndent(out, "", "  ")
 	fmt.Println(string(enc))
-	// tip: schedule via cron and alert if Score >= threshold
-	_ = time.Now()
+	// tip: schedule via cron and alert if Score >= threshold, or run `server` mode instead
 }
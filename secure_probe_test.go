@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeProbeRPC serves just enough JSON-RPC methods for probeOnceWithThresholds
+// (and the codewindow/txdecode scans it calls into) to complete: a fixed
+// balance at "latest" and at the 100-block-ago tag, zero nonce movement, no
+// code, and no logs/transactions. balLatestWei/balPastWei (decimal strings)
+// control the window drop probeOnceWithThresholds computes.
+func fakeProbeRPC(t *testing.T, latest int64, balLatestWei, balPastWei string) *httptest.Server {
+	t.Helper()
+	pastTag := weiToHexTag(latest - 100)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("read request body: %v", err)
+		}
+		var batch []rpcReq
+		single := false
+		if err := json.Unmarshal(body, &batch); err != nil {
+			single = true
+			var req rpcReq
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			batch = []rpcReq{req}
+		}
+		res := make([]rpcRes, len(batch))
+		for i, req := range batch {
+			res[i] = rpcRes{Jsonrpc: "2.0", Id: req.Id, Result: fakeProbeResult(req, pastTag, balLatestWei, balPastWei)}
+		}
+		if single {
+			json.NewEncoder(w).Encode(res[0])
+		} else {
+			json.NewEncoder(w).Encode(res)
+		}
+	}))
+}
+
+func weiToHexTag(n int64) string {
+	return fmt.Sprintf("0x%x", n)
+}
+
+func decToHexQuoted(dec string) json.RawMessage {
+	n, ok := new(big.Int).SetString(dec, 10)
+	if !ok {
+		panic("decToHexQuoted: bad decimal " + dec)
+	}
+	return json.RawMessage(fmt.Sprintf(`"0x%x"`, n))
+}
+
+func fakeProbeResult(req rpcReq, pastTag, balLatestWei, balPastWei string) json.RawMessage {
+	switch req.Method {
+	case "eth_blockNumber":
+		return json.RawMessage(`"` + weiToHexTag(1000) + `"`)
+	case "eth_getBalance":
+		tag, _ := req.Params[1].(string)
+		if tag == pastTag {
+			return decToHexQuoted(balPastWei)
+		}
+		return decToHexQuoted(balLatestWei)
+	case "eth_getTransactionCount":
+		return json.RawMessage(`"0x1"`)
+	case "eth_getCode":
+		return json.RawMessage(`"0x"`)
+	case "eth_getLogs":
+		return json.RawMessage(`[]`)
+	case "eth_getBlockByNumber":
+		return json.RawMessage(`{"transactions":[]}`)
+	default:
+		return json.RawMessage(`null`)
+	}
+}
+
+// TestProbeOnceWithThresholdsWindowDropMatchesBaselineScale exercises the
+// real probeOnceWithThresholds call site (not synthetic same-scale numbers
+// fed straight into outflowZScore) to guard against the window-drop and the
+// baseline it's z-scored against drifting onto different timescales again:
+// history here carries WindowDrop values on the same 100-block-window scale
+// probeOnceWithThresholds itself computes, so a drop that's typical for that
+// scale must not come back inflated into a spurious high-severity alert.
+func TestProbeOnceWithThresholdsWindowDropMatchesBaselineScale(t *testing.T) {
+	const oneEthWei = "1000000000000000000"
+	srv := fakeProbeRPC(t, 1000, "999000000000000000000", "1000000000000000000000") // drop of 1 ETH over the window
+	defer srv.Close()
+
+	history := []StoreRecord{
+		{WindowDrop: "990000000000000000"},
+		{WindowDrop: "1010000000000000000"},
+		{WindowDrop: "995000000000000000"},
+		{WindowDrop: "1005000000000000000"},
+		{WindowDrop: "1000000000000000000"},
+	}
+
+	report, err := probeOnceWithThresholds(srv.URL, "0xabc", ChainThresholds{}, history)
+	if err != nil {
+		t.Fatalf("probeOnceWithThresholds: %v", err)
+	}
+	if report.WindowDropWei != oneEthWei {
+		t.Fatalf("WindowDropWei = %s, want %s", report.WindowDropWei, oneEthWei)
+	}
+	for _, reason := range report.Reasons {
+		if strings.Contains(reason, "standard deviations above") {
+			t.Fatalf("got a z-score alert (%q) for a drop in line with the baseline scale; outflowZScore and probeOnceWithThresholds disagree on units again", reason)
+		}
+	}
+}
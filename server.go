@@ -0,0 +1,409 @@
+// server.go — `server` subcommand: runs the heuristics in secure_probe.go
+// on a timer per configured address, keeps the last N reports per address
+// in memory for diffing, serves them over HTTP/JSON and gRPC (grpc.go), and
+// fires a webhook when a report's score crosses the configured threshold.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// server holds the running daemon's state: config, logger, and the
+// in-memory report history used for diffing and webhook dedup.
+type server struct {
+	cfg   Config
+	log   *logrus.Logger
+	store Store // nil if cfg.StorePath is unset: falls back to the old stateless-per-cycle behavior
+
+	mu      sync.Mutex
+	history map[string][]RiskReport // keyed by address
+}
+
+func runServer(args []string) error {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	configPath := fs.String("config", "probe.json", "path to JSON config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	logOut := io.Writer(os.Stderr)
+	if cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("open log file: %w", err)
+		}
+		logOut = f
+	}
+	logger := logrus.New()
+	logger.Out = logOut
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	logger.SetLevel(level)
+
+	s := &server{
+		cfg:     cfg,
+		log:     logger,
+		history: make(map[string][]RiskReport),
+	}
+	if cfg.StorePath != "" {
+		store, err := NewStore(cfg.StoreBackend, cfg.StorePath)
+		if err != nil {
+			return fmt.Errorf("open store: %w", err)
+		}
+		s.store = store
+	}
+	s.log.WithFields(logrus.Fields{
+		"httpBind": cfg.HTTPBindAddr, "grpcBind": cfg.GRPCBindAddr,
+		"threshold": cfg.Threshold, "pollInterval": cfg.PollInterval(),
+	}).Info("starting daemon")
+
+	if cfg.TLSCertPath != "" || cfg.TLSSelfSign {
+		if cfg.TLSSelfSign {
+			if _, err := os.Stat(cfg.TLSCertPath); err != nil {
+				if err := generateSelfSignedCert(cfg.TLSCertPath, cfg.TLSKeyPath); err != nil {
+					return fmt.Errorf("generate self-signed cert: %w", err)
+				}
+				s.log.Infof("generated self-signed dev cert at %s", cfg.TLSCertPath)
+			}
+		}
+	}
+
+	for _, ep := range cfg.Endpoints {
+		if ep.ChainID != 0 {
+			if untrusted, reason := verifyChain(ep.ChainConfig); untrusted {
+				s.log.Errorf("endpoint %s failed chain verification, skipping: %s", ep.RPCURL, reason)
+				continue
+			}
+		}
+		for _, addr := range ep.Addresses {
+			go s.pollLoop(ep, addr)
+		}
+	}
+
+	if cfg.GRPCBindAddr != "" {
+		go func() {
+			if err := s.serveGRPC(); err != nil {
+				s.log.Errorf("grpc server stopped: %v", err)
+			}
+		}()
+	}
+
+	return s.serveHTTP()
+}
+
+// pollLoop runs one probe cycle immediately, then keeps running cycles
+// either on new block headers (ws:// / wss:// endpoints, via wsPollLoop) or
+// on the fixed cfg.PollInterval timer (timerPollLoop).
+func (s *server) pollLoop(ep EndpointConfig, addr string) {
+	s.runProbeCycle(ep, addr)
+	if ep.WSURL != "" {
+		s.wsPollLoop(ep, addr)
+		return
+	}
+	s.timerPollLoop(ep, addr)
+}
+
+func (s *server) timerPollLoop(ep EndpointConfig, addr string) {
+	interval := s.cfg.PollInterval()
+	for {
+		time.Sleep(interval)
+		s.runProbeCycle(ep, addr)
+	}
+}
+
+// wsPollLoop reacts to new block headers on ep.WSURL (a ws(s):// companion
+// to ep.RPCURL - most nodes expose eth_subscribe on a separate port/scheme
+// from plain JSON-RPC) instead of waiting out the fixed poll interval, so
+// the daemon notices a drain within one block instead of up to
+// cfg.PollIntervalSeconds late. probeOnceWithThresholds still calls
+// ep.RPCURL for every data call; WSURL only drives cycle timing. Falls back
+// to timerPollLoop if the subscription can't be established or drops (e.g.
+// the node doesn't support eth_subscribe).
+func (s *server) wsPollLoop(ep EndpointConfig, addr string) {
+	heads, err := clientFor(ep.WSURL).SubscribeNewHeads(context.Background())
+	if err != nil {
+		s.log.Errorf("newHeads subscription failed ws=%s addr=%s err=%v; falling back to polling", ep.WSURL, addr, err)
+		s.timerPollLoop(ep, addr)
+		return
+	}
+	for range heads {
+		s.runProbeCycle(ep, addr)
+	}
+	s.log.Errorf("newHeads subscription closed ws=%s addr=%s; falling back to polling", ep.WSURL, addr)
+	s.timerPollLoop(ep, addr)
+}
+
+// runProbeCycle runs one probe, persists/dedups it against the store if
+// configured, records it for /probe's history, and fires the webhook if the
+// result crosses (or is still newly above) threshold.
+func (s *server) runProbeCycle(ep EndpointConfig, addr string) {
+	history := s.historyFor(ep.ChainID, addr)
+	report, err := probeOnceWithThresholds(ep.RPCURL, addr, ep.Thresholds, history)
+	if err != nil {
+		s.log.Errorf("probe failed rpc=%s addr=%s err=%v", ep.RPCURL, addr, err)
+		return
+	}
+
+	prev := s.persistReport(ep, addr, history, report)
+	s.recordReport(addr, report)
+
+	alert := report.Score >= s.cfg.Threshold
+	if s.store != nil {
+		alert = shouldAlert(prev, report, s.cfg.Threshold) // store-backed dedup instead of firing every cycle
+	}
+	if alert {
+		s.fireWebhook(report)
+	}
+}
+
+// historyFor returns this address's persisted cycles for z-scoring, or nil
+// if no store is configured. Shared by runProbeCycle, the /probe handler,
+// and grpc.go's Probe/Watch so they all feed probeOnceWithThresholds the
+// same baseline.
+func (s *server) historyFor(chainID int64, addr string) []StoreRecord {
+	if s.store == nil {
+		return nil
+	}
+	history, err := s.store.History(chainID, addr, 200)
+	if err != nil {
+		s.log.Errorf("store history failed addr=%s err=%v", addr, err)
+	}
+	return history
+}
+
+// persistReport appends report's cycle to the store, returning the most
+// recent prior record (if any) for shouldAlert's dedup check. A nil store is
+// a no-op. history is the same slice already fetched (via historyFor) for
+// probeOnceWithThresholds's z-score baseline, reused here to avoid a second
+// History() round trip.
+func (s *server) persistReport(ep EndpointConfig, addr string, history []StoreRecord, report RiskReport) *StoreRecord {
+	if s.store == nil {
+		return nil
+	}
+	var prev *StoreRecord
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		prev = &last
+	}
+
+	rec := StoreRecord{
+		ChainID:    ep.ChainID,
+		Address:    addr,
+		Block:      report.Block,
+		Balance:    report.BalanceWei,
+		WindowDrop: report.WindowDropWei,
+		Nonce:      report.Nonce,
+		Score:      report.Score,
+		Reasons:    report.Reasons,
+		Timestamp:  time.Now().Unix(),
+	}
+	if err := s.store.Append(rec); err != nil {
+		s.log.Errorf("store append failed addr=%s err=%v", addr, err)
+	}
+	return prev
+}
+
+func (s *server) recordReport(addr string, report RiskReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hist := append(s.history[addr], report)
+	if max := s.cfg.HistorySize; len(hist) > max {
+		hist = hist[len(hist)-max:]
+	}
+	s.history[addr] = hist
+}
+
+func (s *server) reportHistory(addr string) []RiskReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RiskReport(nil), s.history[addr]...)
+}
+
+func (s *server) fireWebhook(report RiskReport) {
+	if s.cfg.Webhook.URL == "" {
+		return
+	}
+	var body interface{}
+	switch s.cfg.Webhook.Kind {
+	case "slack":
+		body = map[string]string{"text": fmt.Sprintf("risk score %d for %s: %v", report.Score, report.Address, report.Reasons)}
+	case "discord":
+		body = map[string]string{"content": fmt.Sprintf("risk score %d for %s: %v", report.Score, report.Address, report.Reasons)}
+	default:
+		body = report
+	}
+	payload, _ := json.Marshal(body)
+	resp, err := http.Post(s.cfg.Webhook.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		s.log.Errorf("webhook post failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *server) serveHTTP() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/probe/", func(w http.ResponseWriter, r *http.Request) {
+		addr := r.URL.Path[len("/probe/"):]
+		ep, ok := s.endpointFor(addr)
+		if !ok {
+			http.Error(w, "address not configured", http.StatusNotFound)
+			return
+		}
+		history := s.historyFor(ep.ChainID, addr)
+		report, err := probeOnceWithThresholds(ep.RPCURL, addr, ep.Thresholds, history)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		s.recordReport(addr, report)
+		json.NewEncoder(w).Encode(report)
+	})
+	mux.HandleFunc("/probe-multichain/", func(w http.ResponseWriter, r *http.Request) {
+		addr := r.URL.Path[len("/probe-multichain/"):]
+		chains := s.chainsFor(addr)
+		if len(chains) == 0 {
+			http.Error(w, "address not configured", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(ProbeAllChains(chains, addr))
+	})
+	mux.HandleFunc("/history/", func(w http.ResponseWriter, r *http.Request) {
+		if s.store == nil {
+			http.Error(w, "no storePath configured", http.StatusNotFound)
+			return
+		}
+		addr := r.URL.Path[len("/history/"):]
+		ep, ok := s.endpointFor(addr)
+		if !ok {
+			http.Error(w, "address not configured", http.StatusNotFound)
+			return
+		}
+		history, err := s.store.History(ep.ChainID, addr, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(history)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		clients := make([]*Client, len(s.cfg.Endpoints))
+		for i, ep := range s.cfg.Endpoints {
+			clients[i] = clientFor(ep.RPCURL)
+		}
+		writeMetrics(w, clients)
+	})
+
+	addr := s.cfg.HTTPBindAddr
+	if addr == "" {
+		addr = ":8443"
+	}
+	if s.cfg.TLSCertPath != "" || s.cfg.TLSSelfSign {
+		return http.ListenAndServeTLS(addr, s.cfg.TLSCertPath, s.cfg.TLSKeyPath, mux)
+	}
+	return http.ListenAndServe(addr, mux)
+}
+
+// endpointFor finds the configured endpoint that watches addr.
+func (s *server) endpointFor(addr string) (EndpointConfig, bool) {
+	for _, ep := range s.cfg.Endpoints {
+		for _, a := range ep.Addresses {
+			if a == addr {
+				return ep, true
+			}
+		}
+	}
+	return EndpointConfig{}, false
+}
+
+// chainsFor returns the ChainConfig of every configured endpoint that
+// watches addr, i.e. the chains /probe-multichain/ should fan out to for
+// it. Like endpointFor, this is the gate that keeps the handler from
+// turning an arbitrary path segment into an unauthenticated multi-chain
+// burst of eth_chainId/checkpoint/probe RPC calls against every
+// configured endpoint, watched or not.
+func (s *server) chainsFor(addr string) []ChainConfig {
+	var chains []ChainConfig
+	for _, ep := range s.cfg.Endpoints {
+		for _, a := range ep.Addresses {
+			if a == addr {
+				chains = append(chains, ep.ChainConfig)
+				break
+			}
+		}
+	}
+	return chains
+}
+
+// generateSelfSignedCert writes a throwaway ECDSA cert/key pair to certPath
+// and keyPath for local dev, using only the standard library.
+func generateSelfSignedCert(certPath, keyPath string) error {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "secure-probe-go dev"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		return err
+	}
+	certOut, err := os.Create(certPath)
+	if err != nil {
+		return err
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		return err
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	keyOut, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer keyOut.Close()
+	return pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+}
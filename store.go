@@ -0,0 +1,187 @@
+// store.go — persists probe history across daemon cycles so the server
+// subcommand stops being stateless: every cycle's (block, balance,
+// 100-block-window balance drop, nonce, score, reasons) is recorded per
+// (chainID, address), which lets it (1) compute a longer moving-average
+// baseline and flag window-drop deviations by z-score instead of the
+// hard-coded "any balance decrease" check in probeOnceWithThresholds, and
+// (2) only re-fire a webhook when the score newly crosses threshold or the
+// reason set changes, instead of every cycle it's still above threshold.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StoreRecord is one persisted probe cycle for one (chainID, address).
+type StoreRecord struct {
+	ChainID    int64    `json:"chainId"`
+	Address    string   `json:"address"`
+	Block      string   `json:"block"`
+	Balance    string   `json:"balance"`    // wei, decimal string (big.Int.String())
+	WindowDrop string   `json:"windowDrop"` // wei, decimal string: the same 100-block-window balance drop outflowZScore baselines on (positive => drained); see probeOnceWithThresholds
+	Nonce      int64    `json:"nonce"`
+	Score      int      `json:"score"`
+	Reasons    []string `json:"reasons"`
+	Timestamp  int64    `json:"timestamp"` // unix seconds
+}
+
+// Store is a pluggable backend for StoreRecord history.
+type Store interface {
+	Append(rec StoreRecord) error
+	History(chainID int64, addr string, limit int) ([]StoreRecord, error)
+	Close() error
+}
+
+// NewStore opens the configured backend: "file" (a stdlib-only JSON-lines
+// file, store.go), "sqlite" (modernc.org/sqlite, store_sqlite.go), or "bolt"
+// (go.etcd.io/bbolt, store_bolt.go). All three implement the same Store
+// interface, so callers (server.go) don't change with StoreBackend.
+func NewStore(kind, path string) (Store, error) {
+	switch kind {
+	case "", "file":
+		return newFileStore(path)
+	case "sqlite":
+		return newSQLiteStore(path)
+	case "bolt":
+		return newBoltStore(path)
+	default:
+		return nil, fmt.Errorf("store: unknown backend %q", kind)
+	}
+}
+
+// fileStore appends one JSON object per line to path, guarded by a mutex
+// since the daemon's poll loops run concurrently per address.
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("store: open %s: %w", path, err)
+	}
+	f.Close()
+	return &fileStore{path: path}, nil
+}
+
+func (s *fileStore) Append(rec StoreRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(rec)
+}
+
+func (s *fileStore) History(chainID int64, addr string, limit int) ([]StoreRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var matched []StoreRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var rec StoreRecord
+		if err := dec.Decode(&rec); err != nil {
+			break
+		}
+		if rec.ChainID == chainID && strings.EqualFold(rec.Address, addr) {
+			matched = append(matched, rec)
+		}
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+	return matched, nil
+}
+
+func (s *fileStore) Close() error { return nil }
+
+// outflowZScore computes the z-score of latestWindowDrop against the
+// mean/stddev of history's own WindowDrop values, so a drop only scores as
+// anomalous once it's statistically unusual for this address rather than on
+// any decrease. Both latestWindowDrop and every history[i].WindowDrop must
+// be the same quantity measured the same way - probeOnceWithThresholds's
+// 100-block-window balance drop - not each cycle's raw point-in-time
+// balance: history is appended once per PollInterval (60s by default),
+// which is a much shorter, unrelated timescale from the ~20-minute,
+// 100-block window the drop itself is measured over, so diffing
+// consecutive balances here would baseline "change per poll" and then
+// compare it against "change per 100 blocks" - two different units wearing
+// the same z-score.
+func outflowZScore(history []StoreRecord, latestWindowDrop *big.Float) (z float64, ok bool) {
+	if len(history) < 3 {
+		return 0, false
+	}
+	var drops []float64
+	for _, rec := range history {
+		d, okD := new(big.Float).SetString(rec.WindowDrop)
+		if !okD {
+			continue
+		}
+		f, _ := d.Float64()
+		drops = append(drops, f)
+	}
+	if len(drops) < 2 {
+		return 0, false
+	}
+	var sum float64
+	for _, f := range drops {
+		sum += f
+	}
+	mean := sum / float64(len(drops))
+	var sumSq float64
+	for _, f := range drops {
+		d := f - mean
+		sumSq += d * d
+	}
+	stddev := math.Sqrt(sumSq / float64(len(drops)))
+	if stddev == 0 {
+		return 0, false
+	}
+	cur, _ := latestWindowDrop.Float64()
+	return (cur - mean) / stddev, true
+}
+
+const outflowZScoreAlert = 2.5
+
+// shouldAlert implements dedup: fire only when the score newly crosses
+// threshold (it wasn't already >= threshold last cycle), or the reason set
+// changed even though the score is still above threshold.
+func shouldAlert(prev *StoreRecord, cur RiskReport, threshold int) bool {
+	if cur.Score < threshold {
+		return false
+	}
+	if prev == nil || prev.Score < threshold {
+		return true
+	}
+	return !sameReasonSet(prev.Reasons, cur.Reasons)
+}
+
+func sameReasonSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, r := range a {
+		seen[r] = true
+	}
+	for _, r := range b {
+		if !seen[r] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+// store_bolt.go — the "bolt" Store backend (store.go), backed by
+// go.etcd.io/bbolt. Bolt is a plain key/value store, so unlike
+// store_sqlite.go there's no schema to migrate: each (chainID, address) pair
+// gets its own bucket (created on first Append), and records are stored
+// under their bucket-local auto-increment sequence number so History's
+// ForEach walks them back out in the order they were appended.
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.etcd.io/bbolt"
+)
+
+type boltStore struct {
+	db *bbolt.DB
+}
+
+func newBoltStore(path string) (*boltStore, error) {
+	db, err := bbolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: open bolt %s: %w", path, err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func boltBucketName(chainID int64, addr string) []byte {
+	return []byte(fmt.Sprintf("%d|%s", chainID, strings.ToLower(addr)))
+}
+
+func (s *boltStore) Append(rec StoreRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucketName(rec.ChainID, rec.Address))
+		if err != nil {
+			return err
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, data)
+	})
+}
+
+func (s *boltStore) History(chainID int64, addr string, limit int) ([]StoreRecord, error) {
+	var matched []StoreRecord
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltBucketName(chainID, addr))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			var rec StoreRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			matched = append(matched, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+	return matched, nil
+}
+
+func (s *boltStore) Close() error { return s.db.Close() }
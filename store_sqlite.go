@@ -0,0 +1,82 @@
+// store_sqlite.go — the "sqlite" Store backend (store.go), backed by
+// modernc.org/sqlite (pure Go, no cgo) so the binary stays easy to
+// cross-compile. Schema is embedded and applied at open time via
+// CREATE ... IF NOT EXISTS, so opening an existing store file is a no-op
+// migration and opening a fresh path creates the table.
+package main
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var sqliteSchema string
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("store: open sqlite %s: %w", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: migrate sqlite schema: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Append(rec StoreRecord) error {
+	reasons, err := json.Marshal(rec.Reasons)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO records (chain_id, address, block, balance, window_drop, nonce, score, reasons, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ChainID, strings.ToLower(rec.Address), rec.Block, rec.Balance, rec.WindowDrop, rec.Nonce, rec.Score, string(reasons), rec.Timestamp,
+	)
+	return err
+}
+
+func (s *sqliteStore) History(chainID int64, addr string, limit int) ([]StoreRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT chain_id, address, block, balance, window_drop, nonce, score, reasons, timestamp
+		 FROM records WHERE chain_id = ? AND address = ? ORDER BY id ASC`,
+		chainID, strings.ToLower(addr),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matched []StoreRecord
+	for rows.Next() {
+		var rec StoreRecord
+		var reasons string
+		if err := rows.Scan(&rec.ChainID, &rec.Address, &rec.Block, &rec.Balance, &rec.WindowDrop, &rec.Nonce, &rec.Score, &reasons, &rec.Timestamp); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(reasons), &rec.Reasons); err != nil {
+			return nil, err
+		}
+		matched = append(matched, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+	return matched, nil
+}
+
+func (s *sqliteStore) Close() error { return s.db.Close() }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func mkHistory(windowDrops ...string) []StoreRecord {
+	recs := make([]StoreRecord, len(windowDrops))
+	for i, d := range windowDrops {
+		recs[i] = StoreRecord{WindowDrop: d}
+	}
+	return recs
+}
+
+func TestOutflowZScoreInsufficientHistory(t *testing.T) {
+	// fewer than 3 records: not enough to compute a baseline at all
+	history := mkHistory("100", "90")
+	if _, ok := outflowZScore(history, big.NewFloat(50)); ok {
+		t.Fatal("outflowZScore: ok = true with < 3 history records, want false")
+	}
+}
+
+func TestOutflowZScoreInsufficientOutflowSamples(t *testing.T) {
+	// 3 records but unparseable window drops leave < 2 usable samples
+	history := []StoreRecord{{WindowDrop: "100"}, {WindowDrop: "not-a-number"}, {WindowDrop: "not-a-number-either"}}
+	if _, ok := outflowZScore(history, big.NewFloat(10)); ok {
+		t.Fatal("outflowZScore: ok = true with < 2 parseable window-drop samples, want false")
+	}
+}
+
+func TestOutflowZScoreZeroStddev(t *testing.T) {
+	// every cycle drops by exactly the same amount => stddev is 0, must not divide by zero
+	history := mkHistory("10", "10", "10", "10")
+	if _, ok := outflowZScore(history, big.NewFloat(10)); ok {
+		t.Fatal("outflowZScore: ok = true with zero stddev, want false")
+	}
+}
+
+func TestOutflowZScoreFlagsAnomalousDrop(t *testing.T) {
+	// small window drops with some natural variance (8-12/window) establish
+	// the baseline, then a huge one-off drop should come back with a large
+	// positive z-score
+	history := mkHistory("10", "9", "11", "8", "12")
+	z, ok := outflowZScore(history, big.NewFloat(500))
+	if !ok {
+		t.Fatal("outflowZScore: ok = false, want true")
+	}
+	if z <= outflowZScoreAlert {
+		t.Fatalf("z = %.2f, want > %.2f for a huge outlier window drop", z, outflowZScoreAlert)
+	}
+}
+
+func TestOutflowZScoreDoesNotFlagTypicalOutflow(t *testing.T) {
+	history := mkHistory("10", "9", "11", "8", "12")
+	z, ok := outflowZScore(history, big.NewFloat(10)) // right at the established baseline
+	if !ok {
+		t.Fatal("outflowZScore: ok = false, want true")
+	}
+	if z > outflowZScoreAlert {
+		t.Fatalf("z = %.2f, want <= %.2f for a window drop matching the baseline", z, outflowZScoreAlert)
+	}
+}
+
+func TestShouldAlertBelowThreshold(t *testing.T) {
+	if shouldAlert(nil, RiskReport{Score: 10}, 50) {
+		t.Fatal("shouldAlert: got true, want false when score is below threshold")
+	}
+}
+
+func TestShouldAlertFirstCrossing(t *testing.T) {
+	if !shouldAlert(nil, RiskReport{Score: 80}, 50) {
+		t.Fatal("shouldAlert: got false, want true on first crossing with no prior record")
+	}
+	prev := &StoreRecord{Score: 10}
+	if !shouldAlert(prev, RiskReport{Score: 80}, 50) {
+		t.Fatal("shouldAlert: got false, want true when prev was below threshold")
+	}
+}
+
+func TestShouldAlertDedupsUnchangedReasons(t *testing.T) {
+	prev := &StoreRecord{Score: 80, Reasons: []string{"a", "b"}}
+	cur := RiskReport{Score: 85, Reasons: []string{"b", "a"}} // same set, reordered
+	if shouldAlert(prev, cur, 50) {
+		t.Fatal("shouldAlert: got true, want false when still above threshold with an unchanged reason set")
+	}
+}
+
+func TestShouldAlertFiresOnChangedReasons(t *testing.T) {
+	prev := &StoreRecord{Score: 80, Reasons: []string{"a"}}
+	cur := RiskReport{Score: 85, Reasons: []string{"a", "b"}}
+	if !shouldAlert(prev, cur, 50) {
+		t.Fatal("shouldAlert: got false, want true when the reason set changed even though still above threshold")
+	}
+}
+
+func TestSameReasonSet(t *testing.T) {
+	if !sameReasonSet([]string{"a", "b"}, []string{"b", "a"}) {
+		t.Fatal("sameReasonSet: got false, want true for the same set in a different order")
+	}
+	if sameReasonSet([]string{"a"}, []string{"a", "b"}) {
+		t.Fatal("sameReasonSet: got true, want false for different lengths")
+	}
+	if sameReasonSet([]string{"a", "b"}, []string{"a", "c"}) {
+		t.Fatal("sameReasonSet: got true, want false for different elements")
+	}
+}
+
+func TestFileStoreAppendAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newFileStore(dir + "/store.jsonl")
+	if err != nil {
+		t.Fatalf("newFileStore: %v", err)
+	}
+	defer s.Close()
+	testStoreAppendAndHistory(t, s)
+}
+
+func TestSQLiteStoreAppendAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSQLiteStore(dir + "/store.db")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	testStoreAppendAndHistory(t, s)
+}
+
+func TestBoltStoreAppendAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newBoltStore(dir + "/store.bolt")
+	if err != nil {
+		t.Fatalf("newBoltStore: %v", err)
+	}
+	defer s.Close()
+	testStoreAppendAndHistory(t, s)
+}
+
+// testStoreAppendAndHistory exercises the Store interface contract common to
+// all three backends: records are returned in append order, scoped to their
+// (chainID, address), and limit keeps only the most recent entries.
+func testStoreAppendAndHistory(t *testing.T, s Store) {
+	t.Helper()
+	addr := "0xAbC0000000000000000000000000000000000a"
+	for i := 0; i < 5; i++ {
+		rec := StoreRecord{ChainID: 1, Address: addr, Block: "0x1", Balance: "100", Nonce: int64(i), Score: i * 10, Reasons: []string{"r"}, Timestamp: int64(i)}
+		if err := s.Append(rec); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	// a different (chainID, address) must not leak into addr's history
+	if err := s.Append(StoreRecord{ChainID: 2, Address: addr, Balance: "1"}); err != nil {
+		t.Fatalf("Append (other chain): %v", err)
+	}
+	if err := s.Append(StoreRecord{ChainID: 1, Address: "0xOther", Balance: "1"}); err != nil {
+		t.Fatalf("Append (other addr): %v", err)
+	}
+
+	all, err := s.History(1, addr, 0)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("len(all) = %d, want 5", len(all))
+	}
+	for i, rec := range all {
+		if rec.Nonce != int64(i) {
+			t.Fatalf("all[%d].Nonce = %d, want %d (out of append order)", i, rec.Nonce, i)
+		}
+	}
+
+	limited, err := s.History(1, addr, 2)
+	if err != nil {
+		t.Fatalf("History (limited): %v", err)
+	}
+	if len(limited) != 2 || limited[0].Nonce != 3 || limited[1].Nonce != 4 {
+		t.Fatalf("limited = %+v, want the last 2 records (nonce 3, 4)", limited)
+	}
+}
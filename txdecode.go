@@ -0,0 +1,374 @@
+// txdecode.go — decodes raw Ethereum transactions (legacy and typed
+// EIP-1559/2930/4844 envelopes) via a small hand-rolled RLP decoder, and
+// classifies the addr's most recent transactions for drain/sweeper
+// patterns: contract creation, unlimited-amount transfer/approve calls,
+// sweeper-style gas premiums, and same-block multi-destination batching.
+//
+// This would be its own txdecode package in a larger codebase, but the
+// whole tree (probe heuristics, RPC client, ws framing, stores, ...)
+// stays one flat package main: it's a single binary with no internal API
+// boundary worth the indirection, so splitting it up would just be
+// import-path ceremony. See go.mod's own commit message for why that
+// binary takes 3rd-party dependencies despite the single-package layout.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const defaultTxScanBlocks = 20
+
+// ERC-20 method selectors whose last parameter is the uint256 amount.
+const (
+	selTransfer     = "a9059cbb" // transfer(address,uint256)
+	selTransferFrom = "23b872dd" // transferFrom(address,address,uint256)
+	selApprove      = "095ea7b3" // approve(address,uint256)
+)
+
+// DecodedTx is the subset of a transaction's fields this package cares
+// about, decoded straight from its RLP envelope.
+type DecodedTx struct {
+	Hash         string
+	Type         uint8   // 0 = legacy, 1 = EIP-2930, 2 = EIP-1559, 3 = EIP-4844
+	To           *string // nil => contract creation
+	Value        *big.Int
+	GasPrice     *big.Int // legacy, EIP-2930
+	MaxFeePerGas *big.Int // EIP-1559, EIP-4844
+	Input        []byte
+}
+
+// rlpValue is one decoded RLP item: either a byte string or a list of
+// further items.
+type rlpValue struct {
+	isList bool
+	str    []byte
+	list   []rlpValue
+}
+
+// rlpDecodeItem decodes one RLP item starting at data[0] and returns it
+// along with the remaining, undecoded bytes.
+func rlpDecodeItem(data []byte) (rlpValue, []byte, error) {
+	if len(data) == 0 {
+		return rlpValue{}, nil, errors.New("rlp: empty input")
+	}
+	b0 := data[0]
+	switch {
+	case b0 < 0x80:
+		return rlpValue{str: data[0:1]}, data[1:], nil
+	case b0 <= 0xb7:
+		l := int(b0 - 0x80)
+		if len(data) < 1+l {
+			return rlpValue{}, nil, errors.New("rlp: short string")
+		}
+		return rlpValue{str: data[1 : 1+l]}, data[1+l:], nil
+	case b0 <= 0xbf:
+		lenOfLen := int(b0 - 0xb7)
+		if len(data) < 1+lenOfLen {
+			return rlpValue{}, nil, errors.New("rlp: short string length")
+		}
+		l := int(beUint(data[1 : 1+lenOfLen]))
+		start := 1 + lenOfLen
+		if len(data) < start+l {
+			return rlpValue{}, nil, errors.New("rlp: short long string")
+		}
+		return rlpValue{str: data[start : start+l]}, data[start+l:], nil
+	case b0 <= 0xf7:
+		l := int(b0 - 0xc0)
+		if len(data) < 1+l {
+			return rlpValue{}, nil, errors.New("rlp: short list")
+		}
+		items, err := rlpDecodeAll(data[1 : 1+l])
+		if err != nil {
+			return rlpValue{}, nil, err
+		}
+		return rlpValue{isList: true, list: items}, data[1+l:], nil
+	default:
+		lenOfLen := int(b0 - 0xf7)
+		if len(data) < 1+lenOfLen {
+			return rlpValue{}, nil, errors.New("rlp: short list length")
+		}
+		l := int(beUint(data[1 : 1+lenOfLen]))
+		start := 1 + lenOfLen
+		if len(data) < start+l {
+			return rlpValue{}, nil, errors.New("rlp: short long list")
+		}
+		items, err := rlpDecodeAll(data[start : start+l])
+		if err != nil {
+			return rlpValue{}, nil, err
+		}
+		return rlpValue{isList: true, list: items}, data[start+l:], nil
+	}
+}
+
+// rlpDecodeAll decodes a run of concatenated RLP items (e.g. a list's
+// payload) until payload is exhausted.
+func rlpDecodeAll(payload []byte) ([]rlpValue, error) {
+	var items []rlpValue
+	for len(payload) > 0 {
+		item, rest, err := rlpDecodeItem(payload)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		payload = rest
+	}
+	return items, nil
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
+
+// DecodeRawTransaction decodes the hex-encoded raw transaction payload
+// returned by eth_getRawTransactionByHash, handling both the typed-
+// transaction prefix byte (0x01/0x02/0x03) and legacy transactions (which
+// start directly with an RLP list of 9 items).
+func DecodeRawTransaction(hexPayload string) (DecodedTx, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(hexPayload, "0x"))
+	if err != nil {
+		return DecodedTx{}, fmt.Errorf("txdecode: %w", err)
+	}
+	if len(raw) == 0 {
+		return DecodedTx{}, errors.New("txdecode: empty payload")
+	}
+
+	if raw[0] >= 0xc0 {
+		return decodeLegacyTx(raw)
+	}
+	switch raw[0] {
+	case 0x01, 0x02, 0x03:
+		return decodeTypedTx(raw[0], raw[1:])
+	default:
+		return DecodedTx{}, fmt.Errorf("txdecode: unsupported tx type 0x%x", raw[0])
+	}
+}
+
+// decodeLegacyTx decodes a pre-EIP-2718 transaction: an RLP list of
+// [nonce, gasPrice, gas, to, value, data, v, r, s].
+func decodeLegacyTx(raw []byte) (DecodedTx, error) {
+	item, rest, err := rlpDecodeItem(raw)
+	if err != nil {
+		return DecodedTx{}, err
+	}
+	if len(rest) != 0 {
+		return DecodedTx{}, errors.New("txdecode: trailing bytes after legacy tx")
+	}
+	if !item.isList || len(item.list) != 9 {
+		return DecodedTx{}, errors.New("txdecode: legacy tx must be a 9-item RLP list")
+	}
+	return DecodedTx{
+		Type:     0,
+		GasPrice: new(big.Int).SetBytes(item.list[1].str),
+		To:       rlpAddr(item.list[3].str),
+		Value:    new(big.Int).SetBytes(item.list[4].str),
+		Input:    item.list[5].str,
+	}, nil
+}
+
+// decodeTypedTx decodes the RLP-list payload that follows a typed-
+// transaction's single-byte prefix (already stripped by the caller).
+// EIP-2930 (0x01): [chainId, nonce, gasPrice, gas, to, value, data, accessList, v, r, s]
+// EIP-1559 (0x02): [chainId, nonce, maxPriorityFeePerGas, maxFeePerGas, gas, to, value, data, accessList, v, r, s]
+// EIP-4844 (0x03): EIP-1559 fields plus maxFeePerBlobGas, blobVersionedHashes before the signature.
+func decodeTypedTx(txType byte, rest []byte) (DecodedTx, error) {
+	item, trailing, err := rlpDecodeItem(rest)
+	if err != nil {
+		return DecodedTx{}, err
+	}
+	if len(trailing) != 0 {
+		return DecodedTx{}, errors.New("txdecode: trailing bytes after typed tx")
+	}
+	if !item.isList {
+		return DecodedTx{}, errors.New("txdecode: typed tx payload must be an RLP list")
+	}
+	l := item.list
+
+	var toIdx, valueIdx, dataIdx, gasPriceIdx, maxFeeIdx int
+	switch txType {
+	case 0x01:
+		gasPriceIdx, toIdx, valueIdx, dataIdx = 2, 4, 5, 6
+	case 0x02, 0x03:
+		maxFeeIdx, toIdx, valueIdx, dataIdx = 3, 5, 6, 7
+	}
+	if len(l) <= dataIdx {
+		return DecodedTx{}, fmt.Errorf("txdecode: type 0x%x tx has too few fields (%d)", txType, len(l))
+	}
+
+	dtx := DecodedTx{
+		Type:  txType,
+		To:    rlpAddr(l[toIdx].str),
+		Value: new(big.Int).SetBytes(l[valueIdx].str),
+		Input: l[dataIdx].str,
+	}
+	if txType == 0x01 {
+		dtx.GasPrice = new(big.Int).SetBytes(l[gasPriceIdx].str)
+	} else {
+		dtx.MaxFeePerGas = new(big.Int).SetBytes(l[maxFeeIdx].str)
+	}
+	return dtx, nil
+}
+
+// rlpAddr returns nil for an empty `to` field (contract creation) or the
+// 0x-prefixed address otherwise.
+func rlpAddr(b []byte) *string {
+	if len(b) == 0 {
+		return nil
+	}
+	s := "0x" + hex.EncodeToString(b)
+	return &s
+}
+
+// ClassifyTx scores one decoded transaction against the drain/sweeper
+// heuristics: contract creation, unlimited-amount transfer/transferFrom/
+// approve calls, and a gas price more than 3x the block's base fee
+// (a sweeper paying a premium to front-run the victim's other pending txs).
+func ClassifyTx(tx DecodedTx, baseFeePerGas *big.Int) (score int, reasons []string) {
+	if tx.To == nil {
+		score += 10
+		reasons = append(reasons, "contract creation")
+	}
+
+	if len(tx.Input) >= 4 {
+		selector := hex.EncodeToString(tx.Input[:4])
+		switch selector {
+		case selTransfer, selTransferFrom, selApprove:
+			if len(tx.Input) >= 4+32 && isMaxUint256(tx.Input[len(tx.Input)-32:]) {
+				score += 15
+				reasons = append(reasons, fmt.Sprintf("%s called with max-uint256 amount", selectorName(selector)))
+			}
+		}
+	}
+
+	gasPrice := tx.GasPrice
+	if gasPrice == nil {
+		gasPrice = tx.MaxFeePerGas
+	}
+	if baseFeePerGas != nil && baseFeePerGas.Sign() > 0 && gasPrice != nil {
+		threshold := new(big.Int).Mul(baseFeePerGas, big.NewInt(3))
+		if gasPrice.Cmp(threshold) > 0 {
+			score += 10
+			reasons = append(reasons, "gas price > 3x block base fee (sweeper front-run premium)")
+		}
+	}
+	return score, reasons
+}
+
+func isMaxUint256(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, x := range b {
+		if x != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+func selectorName(selector string) string {
+	switch selector {
+	case selTransfer:
+		return "transfer(address,uint256)"
+	case selTransferFrom:
+		return "transferFrom(address,address,uint256)"
+	case selApprove:
+		return "approve(address,uint256)"
+	default:
+		return selector
+	}
+}
+
+// DetectSweepBatching flags multiple transactions from the same address to
+// different `to` contracts within one block: a common sweeper pattern that
+// drains several token balances in a single block before the victim (or a
+// competing sweeper) can react.
+func DetectSweepBatching(block string, txs []DecodedTx) (score int, reasons []string) {
+	distinct := map[string]bool{}
+	for _, tx := range txs {
+		if tx.To != nil {
+			distinct[*tx.To] = true
+		}
+	}
+	if len(distinct) > 1 {
+		score += 10
+		reasons = append(reasons, fmt.Sprintf("block %s: %d distinct destinations from the same address (sweeper batching)", block, len(distinct)))
+	}
+	return score, reasons
+}
+
+// ScanRecentTxs walks back nBlocks from latest, decodes and classifies
+// every transaction sent by addr, and folds in the same-block batching
+// check. It needs two RPC round trips per matching tx (the block's tx
+// list to find hashes from addr, then eth_getRawTransactionByHash for the
+// RLP payload to decode) since most providers don't expose raw RLP
+// directly from eth_getBlockByNumber.
+func ScanRecentTxs(rpc, addr string, latest *big.Int, nBlocks int64) (score int, reasons []string) {
+	ctx := context.Background()
+	client := clientFor(rpc)
+
+	for i := int64(0); i < nBlocks; i++ {
+		blockNum := new(big.Int).Sub(latest, big.NewInt(i))
+		if blockNum.Sign() < 0 {
+			break
+		}
+		tag := fmt.Sprintf("0x%x", blockNum)
+		blockRaw, err := client.Call(ctx, "eth_getBlockByNumber", tag, true)
+		if err != nil {
+			continue
+		}
+		var block struct {
+			BaseFeePerGas string `json:"baseFeePerGas"`
+			Transactions  []struct {
+				Hash string `json:"hash"`
+				From string `json:"from"`
+			} `json:"transactions"`
+		}
+		if err := json.Unmarshal(blockRaw, &block); err != nil {
+			continue
+		}
+		var baseFee *big.Int
+		if block.BaseFeePerGas != "" {
+			baseFee = hexToBig(block.BaseFeePerGas)
+		}
+
+		var matched []DecodedTx
+		for _, t := range block.Transactions {
+			if !strings.EqualFold(t.From, addr) {
+				continue
+			}
+			rawRaw, err := client.Call(ctx, "eth_getRawTransactionByHash", t.Hash)
+			if err != nil {
+				continue
+			}
+			dtx, err := DecodeRawTransaction(strings.Trim(string(rawRaw), `"`))
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("tx %s: decode failed: %v", t.Hash, err))
+				continue
+			}
+			dtx.Hash = t.Hash
+			matched = append(matched, dtx)
+
+			txScore, txReasons := ClassifyTx(dtx, baseFee)
+			score += txScore
+			for _, r := range txReasons {
+				reasons = append(reasons, fmt.Sprintf("tx %s: %s", t.Hash, r))
+			}
+		}
+		if len(matched) > 1 {
+			batchScore, batchReasons := DetectSweepBatching(tag, matched)
+			score += batchScore
+			reasons = append(reasons, batchReasons...)
+		}
+	}
+	return score, reasons
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// --- small RLP encoder, used only to build test vectors for the decoder ---
+
+func rlpEncStr(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+	if len(b) <= 55 {
+		return append([]byte{0x80 + byte(len(b))}, b...)
+	}
+	lb := big.NewInt(int64(len(b))).Bytes()
+	out := append([]byte{0xb7 + byte(len(lb))}, lb...)
+	return append(out, b...)
+}
+
+func rlpEncList(items ...[]byte) []byte {
+	var payload []byte
+	for _, it := range items {
+		payload = append(payload, it...)
+	}
+	if len(payload) <= 55 {
+		return append([]byte{0xc0 + byte(len(payload))}, payload...)
+	}
+	lb := big.NewInt(int64(len(payload))).Bytes()
+	out := append([]byte{0xf7 + byte(len(lb))}, lb...)
+	return append(out, payload...)
+}
+
+func TestRLPDecodeItemSingleByte(t *testing.T) {
+	item, rest, err := rlpDecodeItem([]byte{0x01, 0xff})
+	if err != nil {
+		t.Fatalf("rlpDecodeItem: %v", err)
+	}
+	if !bytes.Equal(item.str, []byte{0x01}) || item.isList {
+		t.Fatalf("got %+v, want str=[0x01]", item)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Fatalf("rest = %x, want ff", rest)
+	}
+}
+
+func TestRLPDecodeItemShortString(t *testing.T) {
+	data := rlpEncStr([]byte("hello"))
+	item, rest, err := rlpDecodeItem(data)
+	if err != nil {
+		t.Fatalf("rlpDecodeItem: %v", err)
+	}
+	if string(item.str) != "hello" {
+		t.Fatalf("got %q, want %q", item.str, "hello")
+	}
+	if len(rest) != 0 {
+		t.Fatalf("rest = %x, want empty", rest)
+	}
+}
+
+func TestRLPDecodeItemLongString(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 60) // > 55, forces the long-string form (0xb8 prefix)
+	data := rlpEncStr(payload)
+	if data[0] != 0xb8 {
+		t.Fatalf("test vector bug: prefix = 0x%x, want 0xb8", data[0])
+	}
+	item, rest, err := rlpDecodeItem(data)
+	if err != nil {
+		t.Fatalf("rlpDecodeItem: %v", err)
+	}
+	if !bytes.Equal(item.str, payload) {
+		t.Fatalf("got %d bytes, want %d", len(item.str), len(payload))
+	}
+	if len(rest) != 0 {
+		t.Fatalf("rest = %x, want empty", rest)
+	}
+}
+
+func TestRLPDecodeItemShortList(t *testing.T) {
+	data := rlpEncList(rlpEncStr([]byte("a")), rlpEncStr([]byte("bb")))
+	item, _, err := rlpDecodeItem(data)
+	if err != nil {
+		t.Fatalf("rlpDecodeItem: %v", err)
+	}
+	if !item.isList || len(item.list) != 2 {
+		t.Fatalf("got %+v, want a 2-item list", item)
+	}
+	if string(item.list[0].str) != "a" || string(item.list[1].str) != "bb" {
+		t.Fatalf("got items %q, %q", item.list[0].str, item.list[1].str)
+	}
+}
+
+func TestRLPDecodeItemLongList(t *testing.T) {
+	var items [][]byte
+	for i := 0; i < 20; i++ {
+		items = append(items, rlpEncStr(bytes.Repeat([]byte("z"), 5)))
+	}
+	data := rlpEncList(items...)
+	if data[0] <= 0xf7 {
+		t.Fatalf("test vector bug: prefix 0x%x is not a long-list form", data[0])
+	}
+	item, rest, err := rlpDecodeItem(data)
+	if err != nil {
+		t.Fatalf("rlpDecodeItem: %v", err)
+	}
+	if !item.isList || len(item.list) != 20 {
+		t.Fatalf("got %d items, want 20", len(item.list))
+	}
+	if len(rest) != 0 {
+		t.Fatalf("rest = %x, want empty", rest)
+	}
+}
+
+func TestRLPDecodeItemErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty input", nil},
+		{"truncated short string", []byte{0x85, 'h', 'i'}},  // claims 5 bytes, has 2
+		{"truncated long string length", []byte{0xb8}},      // claims 1 length byte, has none
+		{"truncated long string payload", []byte{0xb8, 60}}, // claims 60-byte payload, has none
+		{"truncated short list", []byte{0xc5, 0x01}},        // claims 5-byte payload, has 1
+		{"truncated long list length", []byte{0xf8}},        // claims 1 length byte, has none
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, _, err := rlpDecodeItem(c.data); err == nil {
+				t.Fatalf("rlpDecodeItem(%x): got nil error, want one", c.data)
+			}
+		})
+	}
+}
+
+func addrBytes(last byte) []byte {
+	b := make([]byte, 20)
+	b[19] = last
+	return b
+}
+
+func TestDecodeRawTransactionLegacy(t *testing.T) {
+	to := addrBytes(0x42)
+	raw := rlpEncList(
+		rlpEncStr([]byte{0x01}),       // nonce
+		rlpEncStr([]byte{0x09}),       // gasPrice
+		rlpEncStr([]byte{0x52, 0x08}), // gas
+		rlpEncStr(to),                 // to
+		rlpEncStr([]byte{0x0a}),       // value
+		rlpEncStr(nil),                // data
+		rlpEncStr([]byte{0x1b}),       // v
+		rlpEncStr([]byte{0x01}),       // r
+		rlpEncStr([]byte{0x02}),       // s
+	)
+	tx, err := DecodeRawTransaction("0x" + hex.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("DecodeRawTransaction: %v", err)
+	}
+	if tx.Type != 0 {
+		t.Fatalf("Type = %d, want 0", tx.Type)
+	}
+	if tx.To == nil || *tx.To != "0x"+hex.EncodeToString(to) {
+		t.Fatalf("To = %v, want 0x%x", tx.To, to)
+	}
+	if tx.GasPrice.Cmp(big.NewInt(9)) != 0 {
+		t.Fatalf("GasPrice = %s, want 9", tx.GasPrice)
+	}
+	if tx.Value.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("Value = %s, want 10", tx.Value)
+	}
+}
+
+func TestDecodeRawTransactionLegacyContractCreation(t *testing.T) {
+	raw := rlpEncList(
+		rlpEncStr([]byte{0x01}),
+		rlpEncStr([]byte{0x09}),
+		rlpEncStr([]byte{0x52, 0x08}),
+		rlpEncStr(nil), // empty `to` => contract creation
+		rlpEncStr([]byte{0x0a}),
+		rlpEncStr([]byte{0xde, 0xad, 0xbe, 0xef}),
+		rlpEncStr([]byte{0x1b}),
+		rlpEncStr([]byte{0x01}),
+		rlpEncStr([]byte{0x02}),
+	)
+	tx, err := DecodeRawTransaction("0x" + hex.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("DecodeRawTransaction: %v", err)
+	}
+	if tx.To != nil {
+		t.Fatalf("To = %v, want nil (contract creation)", *tx.To)
+	}
+}
+
+func TestDecodeRawTransactionEIP1559(t *testing.T) {
+	to := addrBytes(0x7)
+	accessList := rlpEncList() // empty access list
+	raw := append([]byte{0x02}, rlpEncList(
+		rlpEncStr([]byte{0x01}),       // chainId
+		rlpEncStr([]byte{0x01}),       // nonce
+		rlpEncStr([]byte{0x03}),       // maxPriorityFeePerGas
+		rlpEncStr([]byte{0x20}),       // maxFeePerGas
+		rlpEncStr([]byte{0x52, 0x08}), // gas
+		rlpEncStr(to),
+		rlpEncStr([]byte{0x0a}),
+		rlpEncStr([]byte{0xaa, 0xbb}), // data
+		accessList,
+		rlpEncStr([]byte{0x01}), // v
+		rlpEncStr([]byte{0x01}), // r
+		rlpEncStr([]byte{0x02}), // s
+	)...)
+	tx, err := DecodeRawTransaction("0x" + hex.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("DecodeRawTransaction: %v", err)
+	}
+	if tx.Type != 0x02 {
+		t.Fatalf("Type = %d, want 2", tx.Type)
+	}
+	if tx.MaxFeePerGas.Cmp(big.NewInt(0x20)) != 0 {
+		t.Fatalf("MaxFeePerGas = %s, want 32", tx.MaxFeePerGas)
+	}
+	if !bytes.Equal(tx.Input, []byte{0xaa, 0xbb}) {
+		t.Fatalf("Input = %x, want aabb", tx.Input)
+	}
+}
+
+func TestDecodeRawTransactionEIP2930(t *testing.T) {
+	to := addrBytes(0x7)
+	accessList := rlpEncList()
+	raw := append([]byte{0x01}, rlpEncList(
+		rlpEncStr([]byte{0x01}),       // chainId
+		rlpEncStr([]byte{0x01}),       // nonce
+		rlpEncStr([]byte{0x09}),       // gasPrice
+		rlpEncStr([]byte{0x52, 0x08}), // gas
+		rlpEncStr(to),
+		rlpEncStr([]byte{0x0a}),
+		rlpEncStr(nil), // data
+		accessList,
+		rlpEncStr([]byte{0x01}),
+		rlpEncStr([]byte{0x01}),
+		rlpEncStr([]byte{0x02}),
+	)...)
+	tx, err := DecodeRawTransaction("0x" + hex.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("DecodeRawTransaction: %v", err)
+	}
+	if tx.Type != 0x01 {
+		t.Fatalf("Type = %d, want 1", tx.Type)
+	}
+	if tx.GasPrice.Cmp(big.NewInt(9)) != 0 {
+		t.Fatalf("GasPrice = %s, want 9", tx.GasPrice)
+	}
+}
+
+func TestDecodeRawTransactionUnsupportedType(t *testing.T) {
+	_, err := DecodeRawTransaction("0x04ff")
+	if err == nil {
+		t.Fatal("DecodeRawTransaction: got nil error, want one for unsupported type 0x04")
+	}
+}
+
+func TestDecodeRawTransactionEmptyPayload(t *testing.T) {
+	_, err := DecodeRawTransaction("0x")
+	if err == nil {
+		t.Fatal("DecodeRawTransaction: got nil error, want one for empty payload")
+	}
+}
+
+func TestClassifyTxMaxUint256Approve(t *testing.T) {
+	input := append(append([]byte{}, []byte{0x09, 0x5e, 0xa7, 0xb3}...), bytes.Repeat([]byte{0xff}, 32)...)
+	tx := DecodedTx{Input: input, GasPrice: big.NewInt(10)}
+	score, reasons := ClassifyTx(tx, big.NewInt(10))
+	if score < 15 {
+		t.Fatalf("score = %d, want >= 15 for max-uint256 approve", score)
+	}
+	if len(reasons) == 0 {
+		t.Fatal("reasons empty, want at least one")
+	}
+}
+
+func TestClassifyTxGasPriceSweeperPremium(t *testing.T) {
+	tx := DecodedTx{GasPrice: big.NewInt(100)}
+	score, reasons := ClassifyTx(tx, big.NewInt(10)) // 100 > 3*10
+	if score < 10 {
+		t.Fatalf("score = %d, want >= 10 for >3x base fee", score)
+	}
+	if len(reasons) == 0 {
+		t.Fatal("reasons empty, want at least one")
+	}
+}
+
+func TestClassifyTxContractCreation(t *testing.T) {
+	tx := DecodedTx{To: nil}
+	score, _ := ClassifyTx(tx, nil)
+	if score < 10 {
+		t.Fatalf("score = %d, want >= 10 for contract creation", score)
+	}
+}
+
+func TestDetectSweepBatchingMultipleDestinations(t *testing.T) {
+	a, b := "0xaaa", "0xbbb"
+	txs := []DecodedTx{{To: &a}, {To: &b}}
+	score, reasons := DetectSweepBatching("0x1", txs)
+	if score == 0 || len(reasons) == 0 {
+		t.Fatalf("got score=%d reasons=%v, want non-zero for 2 distinct destinations", score, reasons)
+	}
+}
+
+func TestDetectSweepBatchingSingleDestinationNoFlag(t *testing.T) {
+	a := "0xaaa"
+	txs := []DecodedTx{{To: &a}, {To: &a}}
+	score, reasons := DetectSweepBatching("0x1", txs)
+	if score != 0 || len(reasons) != 0 {
+		t.Fatalf("got score=%d reasons=%v, want zero for a single destination", score, reasons)
+	}
+}
+
+func TestIsMaxUint256(t *testing.T) {
+	if isMaxUint256(nil) {
+		t.Fatal("isMaxUint256(nil) = true, want false")
+	}
+	if !isMaxUint256(bytes.Repeat([]byte{0xff}, 32)) {
+		t.Fatal("isMaxUint256(all 0xff) = false, want true")
+	}
+	b := bytes.Repeat([]byte{0xff}, 32)
+	b[0] = 0xfe
+	if isMaxUint256(b) {
+		t.Fatal("isMaxUint256(one non-ff byte) = true, want false")
+	}
+}
@@ -0,0 +1,320 @@
+// ws.go — a minimal RFC 6455 websocket client, just enough to open a
+// persistent JSON-RPC subscription channel to a node's ws(s):// endpoint
+// (eth_subscribe / eth_unsubscribe) without taking a 3rd-party websocket
+// dependency (see the no-3rd-party-deps note at the top of secure_probe.go).
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// wsConn is one open websocket connection: enough framing to send
+// unfragmented masked text frames and read (possibly fragmented,
+// never-masked) server frames.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	// writeMu serializes writeText across the goroutines sharing this
+	// connection: Client.subscribe is called once per watched address
+	// (server.go's per-address pollLoop goroutines) against the single
+	// *Client cached per WSURL, so multiple eth_subscribe calls can race
+	// onto the same conn. Two interleaved frame writes corrupt the whole
+	// stream for every subscriber multiplexed on it, not just the racing
+	// pair, so the lock must span the full header+mask+payload write.
+	writeMu sync.Mutex
+}
+
+func dialWebSocket(rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if !strings.Contains(addr, ":") {
+		if u.Scheme == "wss" {
+			addr += ":443"
+		} else {
+			addr += ":80"
+		}
+	}
+
+	var conn net.Conn
+	if u.Scheme == "wss" {
+		conn, err = tls.Dial("tcp", addr, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+	path := u.RequestURI()
+	if path == "" {
+		path = "/"
+	}
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		path, u.Host, key)
+	if _, err := conn.Write([]byte(req)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake: bad Sec-WebSocket-Accept")
+	}
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// writeText sends one unfragmented masked text frame; RFC 6455 requires
+// every client->server frame to be masked.
+func (w *wsConn) writeText(payload []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	var header bytes.Buffer
+	header.WriteByte(0x81) // FIN + text opcode
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	l := len(payload)
+	switch {
+	case l <= 125:
+		header.WriteByte(byte(l) | 0x80)
+	case l <= 65535:
+		header.WriteByte(126 | 0x80)
+		binary.Write(&header, binary.BigEndian, uint16(l))
+	default:
+		header.WriteByte(127 | 0x80)
+		binary.Write(&header, binary.BigEndian, uint64(l))
+	}
+	header.Write(mask)
+	masked := make([]byte, l)
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	if _, err := w.conn.Write(header.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(masked)
+	return err
+}
+
+// readMessage reads one logical message, concatenating continuation
+// frames until FIN. Returns io.EOF on a close frame.
+func (w *wsConn) readMessage() ([]byte, error) {
+	var payload []byte
+	for {
+		hdr := make([]byte, 2)
+		if _, err := io.ReadFull(w.br, hdr); err != nil {
+			return nil, err
+		}
+		fin := hdr[0]&0x80 != 0
+		opcode := hdr[0] & 0x0f
+		length := uint64(hdr[1] & 0x7f)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(w.br, ext); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(w.br, ext); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(w.br, frame); err != nil {
+			return nil, err
+		}
+		if opcode == 0x8 { // close
+			return nil, io.EOF
+		}
+		payload = append(payload, frame...)
+		if fin {
+			break
+		}
+	}
+	return payload, nil
+}
+
+func (w *wsConn) Close() error { return w.conn.Close() }
+
+// ensureWS lazily dials and upgrades the client's websocket connection and
+// starts the notification dispatch loop. Safe to call repeatedly.
+func (c *Client) ensureWS() (*wsConn, error) {
+	c.wsMu.Lock()
+	defer c.wsMu.Unlock()
+	if c.ws != nil {
+		return c.ws, nil
+	}
+	ws, err := dialWebSocket(c.url)
+	if err != nil {
+		return nil, err
+	}
+	c.ws = ws
+	c.subs = make(map[string]chan json.RawMessage)
+	c.pending = make(map[int]chan json.RawMessage)
+	go c.wsReadLoop(ws)
+	return ws, nil
+}
+
+// wsReadLoop dispatches every frame read from ws to either a pending
+// eth_subscribe response (by JSON-RPC id) or a live subscription channel
+// (by eth_subscription subscription id), until the connection breaks.
+func (c *Client) wsReadLoop(ws *wsConn) {
+	for {
+		msg, err := ws.readMessage()
+		if err != nil {
+			c.wsMu.Lock()
+			for _, ch := range c.subs {
+				close(ch)
+			}
+			for _, ch := range c.pending {
+				close(ch)
+			}
+			c.subs, c.pending, c.ws = nil, nil, nil
+			c.wsMu.Unlock()
+			return
+		}
+
+		var msgEnvelope struct {
+			Id     *int            `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Params struct {
+				Subscription string          `json:"subscription"`
+				Result       json.RawMessage `json:"result"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &msgEnvelope); err != nil {
+			continue
+		}
+
+		if msgEnvelope.Method == "eth_subscription" {
+			c.wsMu.Lock()
+			ch, ok := c.subs[msgEnvelope.Params.Subscription]
+			c.wsMu.Unlock()
+			if ok {
+				select {
+				case ch <- msgEnvelope.Params.Result:
+				default: // slow consumer: drop rather than block the read loop
+				}
+			}
+			continue
+		}
+		if msgEnvelope.Id != nil {
+			c.wsMu.Lock()
+			ch, ok := c.pending[*msgEnvelope.Id]
+			delete(c.pending, *msgEnvelope.Id)
+			c.wsMu.Unlock()
+			if ok {
+				ch <- msgEnvelope.Result
+			}
+		}
+	}
+}
+
+// subscribe issues eth_subscribe(kind, extra...) over the client's
+// websocket and returns a channel of each notification's result payload.
+// The channel is closed when the connection breaks; it is never closed by
+// ctx, since eth_unsubscribe still needs the subscription id afterwards.
+func (c *Client) subscribe(ctx context.Context, kind string, extra ...interface{}) (<-chan json.RawMessage, error) {
+	ws, err := c.ensureWS()
+	if err != nil {
+		return nil, err
+	}
+	params := append([]interface{}{kind}, extra...)
+
+	c.wsMu.Lock()
+	c.wsNextID++
+	id := c.wsNextID
+	ack := make(chan json.RawMessage, 1)
+	c.pending[id] = ack
+	c.wsMu.Unlock()
+
+	payload, err := json.Marshal(rpcReq{Jsonrpc: "2.0", Method: "eth_subscribe", Params: params, Id: id})
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.writeText(payload); err != nil {
+		return nil, err
+	}
+
+	select {
+	case result, ok := <-ack:
+		if !ok {
+			return nil, fmt.Errorf("websocket closed before eth_subscribe ack")
+		}
+		var subID string
+		if err := json.Unmarshal(result, &subID); err != nil {
+			return nil, fmt.Errorf("unexpected eth_subscribe result: %s", result)
+		}
+		out := make(chan json.RawMessage, 16)
+		c.wsMu.Lock()
+		c.subs[subID] = out
+		c.wsMu.Unlock()
+		return out, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SubscribeNewHeads subscribes to new block headers.
+func (c *Client) SubscribeNewHeads(ctx context.Context) (<-chan json.RawMessage, error) {
+	return c.subscribe(ctx, "newHeads")
+}
+
+// SubscribeLogs subscribes to logs matching filter (same shape as an
+// eth_getLogs filter object).
+func (c *Client) SubscribeLogs(ctx context.Context, filter map[string]interface{}) (<-chan json.RawMessage, error) {
+	return c.subscribe(ctx, "logs", filter)
+}
+
+// SubscribePendingTxs subscribes to pending transaction hashes as they
+// enter the node's mempool.
+func (c *Client) SubscribePendingTxs(ctx context.Context) (<-chan json.RawMessage, error) {
+	return c.subscribe(ctx, "newPendingTransactions")
+}
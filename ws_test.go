@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// wsFrame builds one raw (unmasked, as real servers send) websocket frame.
+func wsFrame(fin bool, opcode byte, payload []byte) []byte {
+	var buf bytes.Buffer
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	buf.WriteByte(b0)
+
+	l := len(payload)
+	switch {
+	case l <= 125:
+		buf.WriteByte(byte(l))
+	case l <= 65535:
+		buf.WriteByte(126)
+		binary.Write(&buf, binary.BigEndian, uint16(l))
+	default:
+		buf.WriteByte(127)
+		binary.Write(&buf, binary.BigEndian, uint64(l))
+	}
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func newTestWSConn(frames ...[]byte) *wsConn {
+	var raw []byte
+	for _, f := range frames {
+		raw = append(raw, f...)
+	}
+	return &wsConn{br: bufio.NewReader(bytes.NewReader(raw))}
+}
+
+func TestReadMessageSingleFrame(t *testing.T) {
+	w := newTestWSConn(wsFrame(true, 0x1, []byte("hello")))
+	msg, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	if string(msg) != "hello" {
+		t.Fatalf("got %q, want %q", msg, "hello")
+	}
+}
+
+func TestReadMessageFragmented(t *testing.T) {
+	w := newTestWSConn(
+		wsFrame(false, 0x1, []byte("hel")),
+		wsFrame(false, 0x0, []byte("lo, ")),
+		wsFrame(true, 0x0, []byte("world")),
+	)
+	msg, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	if string(msg) != "hello, world" {
+		t.Fatalf("got %q, want %q", msg, "hello, world")
+	}
+}
+
+func TestReadMessageExtended16BitLength(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 200) // > 125, forces the 126 length form
+	w := newTestWSConn(wsFrame(true, 0x1, payload))
+	msg, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	if !bytes.Equal(msg, payload) {
+		t.Fatalf("got %d bytes, want %d", len(msg), len(payload))
+	}
+}
+
+func TestReadMessageExtended64BitLength(t *testing.T) {
+	payload := bytes.Repeat([]byte("y"), 70000) // > 65535, forces the 127 length form
+	w := newTestWSConn(wsFrame(true, 0x1, payload))
+	msg, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	if !bytes.Equal(msg, payload) {
+		t.Fatalf("got %d bytes, want %d", len(msg), len(payload))
+	}
+}
+
+func TestReadMessageCloseFrame(t *testing.T) {
+	w := newTestWSConn(wsFrame(true, 0x8, nil))
+	_, err := w.readMessage()
+	if err != io.EOF {
+		t.Fatalf("got err %v, want io.EOF", err)
+	}
+}
+
+func TestReadMessageMultipleMessagesSequentially(t *testing.T) {
+	w := newTestWSConn(
+		wsFrame(true, 0x1, []byte("first")),
+		wsFrame(true, 0x1, []byte("second")),
+	)
+	msg1, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage 1: %v", err)
+	}
+	if string(msg1) != "first" {
+		t.Fatalf("got %q, want %q", msg1, "first")
+	}
+	msg2, err := w.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage 2: %v", err)
+	}
+	if string(msg2) != "second" {
+		t.Fatalf("got %q, want %q", msg2, "second")
+	}
+}
+
+func TestWSAcceptKeyKnownVector(t *testing.T) {
+	// from the RFC 6455 handshake example itself
+	got := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}